@@ -4,11 +4,11 @@ go 1.19
 
 require (
 	github.com/golang/protobuf v1.5.3
+	golang.org/x/net v0.16.0
 	google.golang.org/grpc v1.60.0
 )
 
 require (
-	golang.org/x/net v0.16.0 // indirect
 	golang.org/x/sys v0.13.0 // indirect
 	golang.org/x/text v0.13.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20231002182017-d307bd883b97 // indirect
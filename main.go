@@ -2,10 +2,20 @@ package main
 
 import (
 	"YoloCache/yolocache"
+	"YoloCache/yolocache/consistenthash"
+	"YoloCache/yolocache/discovery"
+	"YoloCache/yolocache/membership"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var db = map[string]string{
@@ -22,19 +32,114 @@ func createGroup() *yolocache.Group {
 			if v, ok := db[key]; ok {
 				return []byte(v), nil
 			}
-			return nil, fmt.Errorf("%s not exist", key)
-		}))
+			return nil, fmt.Errorf("%s not exist: %w", key, yolocache.ErrNotFound)
+		}),
+		// 不存在的key缓存一分钟，避免反复查询慢数据库
+		yolocache.WithNegativeCache(time.Minute, 1<<10))
 }
 
 // 用来启动缓存服务器：创建 HTTPPool，添加节点信息，注册到 gee 中，启动 HTTP 服务（共3个端口，8001/8002/8003），用户不感知。
-func startCacheServer(addr string, addrs []string, yolo *yolocache.Group) {
-	peers := yolocache.NewHTTPPool(addr)
-	peers.Set(addrs...)
+// gossip 或 disc 不为空时，节点列表由 gossip 或节点发现维护，addrs 被忽略
+func startCacheServer(addr string, addrs []string, yolo *yolocache.Group, placement consistenthash.Placement, gossip string, seeds []string, disc discovery.Discovery) {
+	// 每2秒检查一次其他节点，连续失败3次的节点暂时不再分配key；
+	// 每次请求节点最多等1秒，失败时重试一次，连续失败5次的节点熔断5秒
+	peers := yolocache.NewHTTPPool(addr,
+		yolocache.WithPlacement(placement),
+		yolocache.WithHealthCheck(2*time.Second, 3),
+		yolocache.WithPeerTimeout(time.Second),
+		yolocache.WithRetry(2, 20*time.Millisecond),
+		yolocache.WithCircuitBreaker(5, 5*time.Second),
+		// 与其他节点协商压缩，不小于1KB的值使用 gzip
+		yolocache.WithCompression(0))
+	switch {
+	case gossip != "":
+		joinCluster(addr, gossip, seeds, peers)
+	case disc != nil:
+		go discovery.Watch(context.Background(), disc, 10*time.Second, peers)
+	default:
+		peers.Set(addrs...)
+	}
 	yolo.RegisterPeers(peers)
 	log.Println("yolocache is running at", addr)
 	// peers实现ServeHTTP方法，任何实现了 ServeHTTP 方法的对象都可以作为 HTTP 的 Handler。
-	log.Fatal(http.ListenAndServe(addr[7:], peers))
+	// 节点间通信的 /_yolocache/ 交给 peers 处理，监控指标挂载在 /metrics 上
+	mux := http.NewServeMux()
+	mux.Handle("/_yolocache/", peers)
+	mux.Handle("/metrics", yolocache.MetricsHandler())
+	log.Fatal(http.ListenAndServe(addr[7:], mux))
+
+}
 
+// newDiscovery 解析 -discovery 参数：file:<path>、dns:<name>:<port> 或 srv:<name>，为空时返回 nil
+func newDiscovery(spec string) discovery.Discovery {
+	if spec == "" {
+		return nil
+	}
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "file":
+		return discovery.NewFile(arg)
+	case "dns":
+		name, port, _ := strings.Cut(arg, ":")
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			log.Fatalf("bad -discovery %q: %v", spec, err)
+		}
+		return discovery.NewDNS(name, p)
+	case "srv":
+		return discovery.NewDNSSRV(arg)
+	}
+	log.Fatalf("unknown -discovery %q", spec)
+	return nil
+}
+
+// joinCluster 启动 gossip，加入 seeds 所在的集群，成员的变化会自动同步到 peers
+func joinCluster(addr, gossip string, seeds []string, peers *yolocache.HTTPPool) {
+	ml, err := membership.New(membership.Config{Name: addr, BindAddr: gossip, Peers: peers})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(seeds) > 0 {
+		// 种子节点暂时不可用时只记录日志，之后其他节点加入时仍然可以通过它们发现本节点
+		if _, err := ml.Join(seeds...); err != nil {
+			log.Println("[Membership]", err)
+		}
+	}
+	log.Println("gossip is running at", ml.Addr())
+}
+
+// 与 startCacheServer 相同，但节点间使用 gRPC 通信，地址不带 http:// 前缀
+func startGRPCCacheServer(addr string, addrs []string, yolo *yolocache.Group) {
+	self := strings.TrimPrefix(addr, "http://")
+	peerAddrs := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		peerAddrs = append(peerAddrs, strings.TrimPrefix(a, "http://"))
+	}
+	peers := yolocache.NewGRPCPool(self, yolocache.WithGRPCCompression(0))
+	peers.Set(peerAddrs...)
+	yolo.RegisterPeers(peers)
+	lis, err := net.Listen("tcp", self)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println("yolocache (gRPC) is running at", self)
+	log.Fatal(peers.Serve(lis))
+}
+
+// newPlacement 根据 -placement 参数创建节点选择算法
+func newPlacement(name string) consistenthash.Placement {
+	switch name {
+	case "ring":
+		return consistenthash.New(50, nil)
+	case "rendezvous":
+		return consistenthash.NewRendezvous()
+	case "jump":
+		return consistenthash.NewJump()
+	case "maglev":
+		return consistenthash.NewMaglev(0)
+	}
+	log.Fatalf("unknown placement %q", name)
+	return nil
 }
 
 func startAPIServer(apiaddr string, yolo *yolocache.Group) {
@@ -42,13 +147,18 @@ func startAPIServer(apiaddr string, yolo *yolocache.Group) {
 	http.Handle("/api", http.HandlerFunc(
 		func(writer http.ResponseWriter, request *http.Request) {
 			key := request.URL.Query().Get("key")
-			view, err := yolo.Get(key)
+			view, err := yolo.GetContext(request.Context(), key)
+			if errors.Is(err, yolocache.ErrNotFound) {
+				http.Error(writer, err.Error(), http.StatusNotFound)
+				return
+			}
 			if err != nil {
 				http.Error(writer, err.Error(), http.StatusInternalServerError)
 				return
 			}
 			writer.Header().Set("Content-Type", "application/octet-stream")
-			writer.Write(view.ByteSlice())
+			// Reader 直接读取缓存中的值，不像 ByteSlice 那样先拷贝一份
+			io.Copy(writer, view.Reader())
 		}))
 	log.Println("fontend server is running at", apiaddr)
 	// 使用 http.ListenAndServe 启动一个 HTTP 服务器，监听指定的地址，并使用默认的 nil 处理器（handler）。
@@ -60,6 +170,10 @@ func startAPIServer(apiaddr string, yolo *yolocache.Group) {
 func main() {
 	var port int // 在命令行参数中赋值
 	var api bool
+	var transport string
+	var placement string
+	var gossip, seeds string
+	var disc string
 	/*
 		使用 flag 包来定义一个整数变量 port，并将该变量与命令行参数关联起来。具体来说：
 
@@ -77,6 +191,15 @@ func main() {
 	flag.IntVar(&port, "port", 8001, "Yolocache server port")
 	// 命令行参数，bool
 	flag.BoolVar(&api, "api", false, "Start a api server?")
+	// 节点间的通信方式，http 或 grpc
+	flag.StringVar(&transport, "transport", "http", "Peer transport: http or grpc")
+	// 节点选择算法，所有节点必须使用相同的值
+	flag.StringVar(&placement, "placement", "ring", "Key placement for the http transport: ring, rendezvous, jump or maglev")
+	// 使用 gossip 发现其他节点，而不是下面写死的 addrMap，如 -gossip=127.0.0.1:7001 -seeds=127.0.0.1:7002
+	flag.StringVar(&gossip, "gossip", "", "UDP address for gossip membership (http transport only); empty uses the static peer list")
+	flag.StringVar(&seeds, "seeds", "", "Comma separated gossip addresses of seed nodes")
+	// 从文件或DNS发现节点，如 -discovery=file:/etc/yolocache/peers 或 -discovery=srv:_yolocache._tcp.example.com
+	flag.StringVar(&disc, "discovery", "", "Peer discovery: file:<path>, dns:<name>:<port> or srv:<name> (http transport only)")
 	/*
 		flag.Parse() 是用于解析命令行参数的函数。在使用 flag 包定义命令行标志之后，需要调用 flag.Parse() 来解析命令行参数，并将它们赋值给相应的变量。
 		具体而言，flag.Parse() 将扫描命令行参数列表，并设置已定义标志的值。
@@ -107,5 +230,16 @@ func main() {
 		go startAPIServer(apiAddr, yolo)
 	}
 	// 冗余类型转换 addrs已经是一个[]string
-	startCacheServer(addrMap[port], addrs, yolo)
+	switch transport {
+	case "http":
+		var seedList []string
+		if seeds != "" {
+			seedList = strings.Split(seeds, ",")
+		}
+		startCacheServer(addrMap[port], addrs, yolo, newPlacement(placement), gossip, seedList, newDiscovery(disc))
+	case "grpc":
+		startGRPCCacheServer(addrMap[port], addrs, yolo)
+	default:
+		log.Fatalf("unknown transport %q", transport)
+	}
 }
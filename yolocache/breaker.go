@@ -0,0 +1,215 @@
+package yolocache
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+/*
+***********************节点调用的超时、重试与熔断*********************************
+  - 超时：每次请求节点最多等待的时间，可以为个别节点单独设置
+  - 重试：Get 是幂等的，节点失败（网络错误、超时、5xx）时可以退避一段随机时间后重试；
+    所有者明确回复的结果（不存在、回调函数失败、过载）不会重试
+  - 熔断：每个节点一个熔断器，连续失败 failures 次后打开（open），这段时间内 PickPeer 不再选择这个节点，
+    请求直接回退到其他副本或本地加载；cooldown 之后进入半开（half-open），只放行一个探测请求，
+    成功则关闭（closed），失败则重新打开
+*/
+
+// breakerState 是熔断器的状态
+type breakerState int
+
+const (
+	breakerClosed   breakerState = iota // 正常，所有请求都放行
+	breakerOpen                         // 熔断中，所有请求都拒绝
+	breakerHalfOpen                     // 冷却结束，只放行一个探测请求
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+type breakerConfig struct {
+	failures int           // 连续失败多少次后打开，0 表示不使用熔断器
+	cooldown time.Duration // 打开多久之后进入半开
+}
+
+type retryConfig struct {
+	attempts int           // 包括第一次在内最多请求几次，<= 1 表示不重试
+	backoff  time.Duration // 第一次重试前最多等待的时间，之后每次翻倍
+}
+
+// WithPeerTimeout 设置每次请求其他节点最多等待的时间，超时算作节点的一次失败。d <= 0 表示不限制（默认）
+func WithPeerTimeout(d time.Duration) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.timeout = d
+	}
+}
+
+// WithPeerTimeoutFor 为某一个节点单独设置超时时间，覆盖 WithPeerTimeout，适合比其他节点慢的机器或跨机房的节点
+func WithPeerTimeoutFor(peer string, d time.Duration) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.timeouts[peer] = d
+	}
+}
+
+// WithRetry 让 Get 在节点失败时最多请求 attempts 次（包括第一次），第 i 次重试前随机等待 [0, backoff*2^i) 的时间，
+// 随机化避免多个节点在同一时刻一起重试。Set 和 Remove 不会重试
+func WithRetry(attempts int, backoff time.Duration) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.retry = retryConfig{attempts: attempts, backoff: backoff}
+	}
+}
+
+// WithCircuitBreaker 为每个节点启用熔断器：连续失败 failures 次后熔断 cooldown 的时间，
+// 期间 PickPeer 不会选择这个节点，之后放行一个探测请求，成功后恢复
+func WithCircuitBreaker(failures int, cooldown time.Duration) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.breaker = breakerConfig{failures: failures, cooldown: cooldown}
+	}
+}
+
+// circuitBreaker 是一个节点的熔断器。nil 表示没有启用熔断器，所有方法都可以在 nil 上调用
+type circuitBreaker struct {
+	cfg breakerConfig
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int       // closed 状态下连续失败的次数
+	openedAt time.Time // 最近一次打开的时间
+	probing  bool      // half-open 状态下是否已经放行了探测请求
+}
+
+func newCircuitBreaker(cfg breakerConfig) *circuitBreaker {
+	if cfg.failures <= 0 {
+		return nil
+	}
+	return &circuitBreaker{cfg: cfg}
+}
+
+// available 判断现在是否可以向这个节点发送请求，不改变熔断器的状态，供 PickPeer 使用
+func (b *circuitBreaker) available() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		return time.Since(b.openedAt) >= b.cfg.cooldown
+	case breakerHalfOpen:
+		return !b.probing
+	default:
+		return true
+	}
+}
+
+// allow 在发送请求前调用，返回是否放行。冷却结束后的第一个请求会把熔断器切换到半开，作为探测请求放行
+func (b *circuitBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.cfg.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record 记录一次放行的请求的结果，failure 为 nil 表示节点正常
+func (b *circuitBreaker) record(failure error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if failure == nil {
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.cfg.failures {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// abandon 在放行的请求被调用方取消时调用，结果不能说明节点的好坏，只释放探测的名额
+func (b *circuitBreaker) abandon() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// current 返回熔断器当前的状态，用于输出节点状态
+func (b *circuitBreaker) current() breakerState {
+	if b == nil {
+		return breakerClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// timeoutFor 返回请求 peer 时使用的超时时间，0 表示不限制
+func (p *HTTPPool) timeoutFor(peer string) time.Duration {
+	if d, ok := p.timeouts[peer]; ok {
+		return d
+	}
+	return p.timeout
+}
+
+// wait 返回第 attempt 次重试（从0开始）前等待的时间
+func (r retryConfig) wait(attempt int) time.Duration {
+	if r.backoff <= 0 {
+		return 0
+	}
+	// 最多等待 backoff 的32倍
+	if attempt > 5 {
+		attempt = 5
+	}
+	d := r.backoff << attempt
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+// sleepContext 等待 d 的时间，ctx 先结束时返回 false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
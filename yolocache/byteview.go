@@ -1,5 +1,10 @@
 package yolocache
 
+import (
+	"bytes"
+	"io"
+)
+
 // 使用sync.Mutex封装LRU的几个方法，使之支持并发的读写
 
 /*
@@ -32,3 +37,9 @@ func cloneBytes(b []byte) []byte {
 	copy(c, b)
 	return c
 }
+
+// Reader 返回读取缓存值的 io.Reader。与 ByteSlice 不同，它直接读取b而不拷贝，
+// 适合把很大的值写给 http.ResponseWriter 等场景；bytes.Reader 只读，缓存值同样不会被修改
+func (v ByteView) Reader() io.Reader {
+	return bytes.NewReader(v.b)
+}
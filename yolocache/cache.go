@@ -6,6 +6,8 @@ package yolocache
 import (
 	"YoloCache/yolocache/lru"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // 并发缓存结构体
@@ -15,6 +17,16 @@ type cache struct {
 	// 缓存最大值, 与lru中的maxBytes相同
 	cacheBytes int64
 	once       sync.Once
+	nget, nhit atomic.Int64 // 查询次数和命中次数，lru还没初始化时也要计数，所以没有放在锁里
+}
+
+// CacheStats 是某个缓存在某一时刻的统计快照
+type CacheStats struct {
+	Bytes     int64 `json:"bytes"`     // 当前已使用的内存
+	Items     int64 `json:"items"`     // 当前缓存的记录数
+	Gets      int64 `json:"gets"`      // 查询次数
+	Hits      int64 `json:"hits"`      // 命中次数
+	Evictions int64 `json:"evictions"` // 因超出内存限制而被淘汰的记录数
 }
 
 // 封装get和add方法，并添加互斥锁mu
@@ -42,14 +54,16 @@ type cache struct {
 //
 
 // 最终的修改解决方案
-func (c *cache) add(key string, value ByteView) {
+// expire 为零值时表示永不过期
+func (c *cache) add(key string, value ByteView, expire time.Time) {
+	// 初始化也要在锁内，否则其他协程在锁内读到的 c.lru 与这里的写没有先后关系
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.once.Do(func() {
 		c.lru = lru.New(c.cacheBytes, nil)
 	})
-	c.mu.Lock()
-	defer c.mu.Unlock()
 	// 确保在初始化完成后再执行 Add 操作
-	c.lru.Add(key, value)
+	c.lru.AddWithExpire(key, value, expire)
 }
 
 // TODO 同样存在锁粒度的问题
@@ -104,18 +118,52 @@ func (c *cache) add(key string, value ByteView) {
 
 // TODO 尝试去掉锁
 func (c *cache) get(key string) (value ByteView, ok bool) {
-	// 这里的思想是 双重检查锁定  当然可以用once来优化
-	// 1. 先检查 lru 是否为 nil
+	c.nget.Add(1)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 1. 检查 lru 是否为 nil，不加锁读 c.lru 会与 add 中的初始化产生数据竞争
 	if c.lru == nil {
 		return
 	}
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	// 2. 不加锁了
 	// 4. 获取值
 	if v, ok := c.lru.Get(key); ok {
 		// 5. 类型断言
+		c.nhit.Add(1)
 		return v.(ByteView), ok
 	}
 	return
 }
+
+// removeExpired 清理所有已过期的记录，由后台的清理协程定期调用
+func (c *cache) removeExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lru == nil {
+		return 0
+	}
+	return c.lru.RemoveExpired()
+}
+
+// remove 删除key对应的缓存值
+func (c *cache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lru == nil {
+		return
+	}
+	c.lru.Remove(key)
+}
+
+// stats 返回缓存当前的统计快照
+func (c *cache) stats() CacheStats {
+	st := CacheStats{Gets: c.nget.Load(), Hits: c.nhit.Load()}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lru == nil {
+		return st
+	}
+	st.Bytes = c.lru.Bytes()
+	st.Items = int64(c.lru.Len())
+	st.Evictions = c.lru.Evictions()
+	return st
+}
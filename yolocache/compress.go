@@ -0,0 +1,174 @@
+package yolocache
+
+import (
+	pb "YoloCache/yolocache/yolocachepb"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+)
+
+/*
+***********************节点间传输的压缩*********************************
+缓存的值往往是JSON之类的文本，压缩后只有原来的几分之一。压缩是协商出来的：
+  - 请求方在 accept_encoding 中列出自己能解压的编码（旧版本的节点不会设置，即不接受压缩）
+  - 服务端从双方都接受的编码中按请求方的偏好选一个，只在值不小于 threshold、且压缩后确实变小时才压缩，并在 Response.encoding 中注明
+  - 请求方解压时限制解压后的大小（WithMaxValueSize，默认64MB），出错或恶意的节点发来的压缩炸弹不会耗尽内存
+  - 旧版本的服务端不认识 accept_encoding，返回的 encoding 为空，请求方直接使用原始的值
+所以新旧版本的节点可以混合部署。内置 gzip，也可以用 RegisterCodec 注册其他编码（如 snappy、zstd）。
+*/
+
+// Codec 是节点之间压缩缓存值使用的编码。Name 会写进协议，集群中所有节点上同名的 Codec 必须兼容。
+// Decode 解压后的大小超过 limit 字节时应当返回 ErrValueTooLarge（或包装了它的错误），而不是继续分配内存
+type Codec interface {
+	Name() string
+	Encode(b []byte) ([]byte, error)
+	Decode(b []byte, limit int64) ([]byte, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{gzipCodecName: gzipCodec{}}
+)
+
+// RegisterCodec 注册一个编码，已有同名的编码时覆盖它。应该在创建 HTTPPool 或 GRPCPool 之前调用
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[c.Name()] = c
+}
+
+func lookupCodec(name string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[name]
+	return c, ok
+}
+
+const (
+	gzipCodecName = "gzip"
+	// 默认只压缩不小于1KB的值，更小的值压缩省下的流量抵不上花费的CPU
+	defaultCompressThreshold = 1 << 10
+)
+
+// gzipCodec 使用最快的压缩级别，节点间的请求对延迟比对压缩率更敏感
+type gzipCodec struct{}
+
+var gzipWriters = sync.Pool{New: func() interface{} {
+	w, _ := gzip.NewWriterLevel(nil, gzip.BestSpeed)
+	return w
+}}
+
+func (gzipCodec) Name() string { return gzipCodecName }
+
+func (gzipCodec) Encode(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(w)
+	w.Reset(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decode(b []byte, limit int64) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	// 多读一个字节，才能区分刚好等于 limit 和超过了 limit
+	out, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(out)) > limit {
+		return nil, fmt.Errorf("%w: decoded value exceeds %d bytes", ErrValueTooLarge, limit)
+	}
+	return out, nil
+}
+
+// compression 是一个节点的压缩配置，零值表示不使用压缩：既不请求对方压缩，也不压缩发给对方的值
+type compression struct {
+	threshold int      // 不小于这个大小的值才压缩
+	accept    []string // 本节点接受的编码，按偏好排序
+}
+
+func newCompression(threshold int, names []string) compression {
+	if threshold <= 0 {
+		threshold = defaultCompressThreshold
+	}
+	if len(names) == 0 {
+		names = []string{gzipCodecName}
+	}
+	return compression{threshold: threshold, accept: names}
+}
+
+// WithCompression 开启节点间的压缩：向其他节点请求时声明可以接受 codecs 中的编码（默认 gzip），
+// 回复其他节点时，不小于 threshold 字节（<= 0 时为1KB）的值会用对方接受的编码压缩
+func WithCompression(threshold int, codecs ...string) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.compression = newCompression(threshold, codecs)
+	}
+}
+
+// WithGRPCCompression 与 WithCompression 相同，用于 GRPCPool
+func WithGRPCCompression(threshold int, codecs ...string) GRPCPoolOption {
+	return func(p *GRPCPool) {
+		p.compression = newCompression(threshold, codecs)
+	}
+}
+
+// encode 在服务端调用：从请求方的 accept 和本节点的 c.accept 都有的编码中，按请求方的偏好选一个压缩 res.Value。
+// 只压缩成功的结果，压缩后没有变小时保持原样
+func (c compression) encode(res *pb.Response, accept []string) {
+	if len(c.accept) == 0 || res.GetCode() != pb.Code_OK || len(res.Value) < c.threshold {
+		return
+	}
+	for _, name := range accept {
+		if !c.accepts(name) {
+			continue
+		}
+		codec, ok := lookupCodec(name)
+		if !ok {
+			continue
+		}
+		b, err := codec.Encode(res.Value)
+		if err == nil && len(b) < len(res.Value) {
+			res.Value, res.Encoding = b, name
+		}
+		return
+	}
+}
+
+// accepts 判断本节点是否配置了这个编码
+func (c compression) accepts(name string) bool {
+	for _, n := range c.accept {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeResponse 在请求方调用：解压服务端压缩过的 res.Value，解压后的值不能超过 limit 字节
+func decodeResponse(res *pb.Response, limit int64) error {
+	if res.GetEncoding() == "" {
+		return nil
+	}
+	codec, ok := lookupCodec(res.GetEncoding())
+	if !ok {
+		return fmt.Errorf("unknown value encoding %q", res.GetEncoding())
+	}
+	b, err := codec.Decode(res.Value, limit)
+	if err != nil {
+		return fmt.Errorf("decoding %s value: %w", res.GetEncoding(), err)
+	}
+	res.Value, res.Encoding = b, ""
+	return nil
+}
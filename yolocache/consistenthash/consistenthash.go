@@ -1,7 +1,10 @@
 package consistenthash
 
 import (
+	"encoding/binary"
 	"hash/crc32"
+	"hash/fnv"
+	"math"
 	"sort"
 	"strconv"
 )
@@ -19,7 +22,17 @@ type Map struct {
 	replicas int            // 虚拟节点倍数
 	keys     []int          // 哈希环，使用一个有序的int数组来存储哈希环上的所有节点的哈希值
 	hashMap  map[int]string // 虚拟节点与真实节点的映射表，键是虚拟节点的哈希值，值是真实节点的名称
+	weights  map[string]int // 真实节点的权重，节点的虚拟节点数为 replicas * weight
 
+	// 不同节点的虚拟节点可能算出相同的哈希值（冲突），这时名称较小的节点占据这个位置，
+	// 与添加的顺序无关，保证所有进程得到相同的环
+	refs       map[int]int // 虚拟节点的哈希值 -> 有多少个虚拟节点算出了这个值
+	collisions int         // 因为冲突而没有占到位置的虚拟节点数
+
+	epsilon     float64          // 有界负载的参数，每个节点的负载不超过平均值的 (1+epsilon) 倍，0 表示不限制
+	loads       map[string]int64 // 每个真实节点当前正在处理的请求数
+	totalLoad   int64            // 所有节点的负载之和
+	totalWeight int              // 所有节点的权重之和
 }
 
 func New(replicas int, fn Hash) *Map {
@@ -28,6 +41,9 @@ func New(replicas int, fn Hash) *Map {
 		replicas: replicas,
 		keys:     nil,
 		hashMap:  make(map[int]string),
+		weights:  make(map[string]int),
+		refs:     make(map[int]int),
+		loads:    make(map[string]int64),
 	}
 
 	if m.hash == nil {
@@ -38,31 +54,191 @@ func New(replicas int, fn Hash) *Map {
 	return m
 }
 
-// Add  添加真实节点/机器的Add方法，允许传入0或多个真实节点的名称
+// NewBounded 创建一个有界负载（consistent hashing with bounded loads）的哈希环：
+// GetLeast 选出的节点的负载超过平均值的 (1+epsilon) 倍时，key 会顺时针溢出到环上的下一个节点。
+// epsilon 越小负载越均匀，但溢出的key越多，常用的取值是 0.25
+func NewBounded(replicas int, epsilon float64, fn Hash) *Map {
+	m := New(replicas, fn)
+	m.epsilon = epsilon
+	return m
+}
+
+// Add  添加真实节点/机器的Add方法，允许传入0或多个真实节点的名称，每个节点的权重都是1
 func (m *Map) Add(keys ...string) {
 	// 每个key 代表着真实节点
 	for _, key := range keys {
-		// 为每个真实节点key，创建m.replicas个虚拟节点，
-		for i := 0; i < m.replicas; i++ {
-			// 虚拟节点的名称是：strconv.Itoa(i) + key，即通过添加编号的方式区分不同虚拟节点
-			hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
+		m.addNode(key, 1)
+	}
+	// 在环上，将所有的虚拟节点的哈希值进行排序，方便之后进行二分查找
+	sort.Ints(m.keys)
+}
+
+// AddWeighted 添加一个带权重的真实节点，它的虚拟节点数为 replicas * weight，
+// 所以分到的key的数量大致与权重成正比，适合机器容量不同的集群。节点已存在时更新它的权重
+func (m *Map) AddWeighted(key string, weight int) {
+	if weight <= 0 {
+		return
+	}
+	m.addNode(key, weight)
+	sort.Ints(m.keys)
+}
+
+// Weight 返回节点的权重，节点不存在时返回0
+func (m *Map) Weight(key string) int {
+	return m.weights[key]
+}
+
+// addNode 将节点的虚拟节点加入环中，但不排序，调用方负责排序
+func (m *Map) addNode(key string, weight int) {
+	// 已存在的节点先删除旧的虚拟节点，避免重复添加
+	if _, ok := m.weights[key]; ok {
+		m.Remove(key)
+	}
+	m.weights[key] = weight
+	m.totalWeight += weight
+	m.place(key, weight)
+}
+
+// place 将节点的虚拟节点放到环上，但不排序
+func (m *Map) place(key string, weight int) {
+	// 为每个真实节点key，创建m.replicas * weight个虚拟节点，
+	for i := 0; i < m.replicas*weight; i++ {
+		// 虚拟节点的名称是：strconv.Itoa(i) + key，即通过添加编号的方式区分不同虚拟节点
+		hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
+		m.refs[hash]++
+		owner, ok := m.hashMap[hash]
+		if !ok {
 			// 将虚拟节点的哈希值添加到环上
 			m.keys = append(m.keys, hash)
 			// 在Hashmap中添加虚拟节点和真实节点的映射关系
 			// 将虚拟节点的hash值作为key, 真实节点名（1，2，3.。） 作为value
 			m.hashMap[hash] = key
+			continue
+		}
+		// 位置已被占用：名称较小的节点胜出，结果与添加顺序无关
+		if owner != key {
+			m.collisions++
+			if key < owner {
+				m.hashMap[hash] = key
+			}
 		}
 	}
-	// 在环上，将所有的虚拟节点的哈希值进行排序，方便之后进行二分查找
+}
+
+// rebuild 按节点名称的顺序重新构建整个环，删除了参与冲突的节点后，需要让冲突中落败的节点重新占据位置
+func (m *Map) rebuild() {
+	nodes := make([]string, 0, len(m.weights))
+	for node := range m.weights {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	m.keys = m.keys[:0]
+	m.hashMap = make(map[int]string)
+	m.refs = make(map[int]int)
+	m.collisions = 0
+	for _, node := range nodes {
+		m.place(node, m.weights[node])
+	}
 	sort.Ints(m.keys)
 }
 
+// Collisions 返回因为哈希冲突而没有占到位置的虚拟节点数，不为0时说明 replicas 过大或哈希函数的分布不好
+func (m *Map) Collisions() int {
+	return m.collisions
+}
+
+// Digest 返回整个环（每个虚拟节点的位置及其所属的真实节点）的摘要，
+// 节点之间比较摘要就能知道它们对每个key的所有者的判断是否一致
+func (m *Map) Digest() uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, hash := range m.keys {
+		binary.BigEndian.PutUint64(buf[:], uint64(hash))
+		h.Write(buf[:])
+		h.Write([]byte(m.hashMap[hash]))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+// Remove 删除真实节点及其所有虚拟节点，不影响环上的其他节点，
+// 原本属于被删除节点的key会落到环上的下一个节点，其他key的归属保持不变
+func (m *Map) Remove(keys ...string) {
+	removed := make(map[int]bool)
+	collided := false
+	for _, key := range keys {
+		weight, ok := m.weights[key]
+		if !ok {
+			continue
+		}
+		delete(m.weights, key)
+		m.totalWeight -= weight
+		// 被删除节点上的负载也不再计入
+		m.totalLoad -= m.loads[key]
+		delete(m.loads, key)
+		for i := 0; i < m.replicas*weight; i++ {
+			hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
+			m.refs[hash]--
+			if m.refs[hash] > 0 {
+				// 这个位置还有其他节点的虚拟节点，可能需要换一个所有者
+				collided = true
+				continue
+			}
+			delete(m.refs, hash)
+			delete(m.hashMap, hash)
+			removed[hash] = true
+		}
+	}
+	if collided {
+		m.rebuild()
+		return
+	}
+	if len(removed) == 0 {
+		return
+	}
+	// 原地过滤，keys仍然保持有序
+	kept := m.keys[:0]
+	for _, hash := range m.keys {
+		if !removed[hash] {
+			kept = append(kept, hash)
+		}
+	}
+	m.keys = kept
+}
+
 // Get 实现选择节点的Get方法
 func (m *Map) Get(key string) string {
 	// 如果哈希环为空，就直接返回空
 	if len(m.keys) == 0 {
 		return ""
 	}
+	return m.hashMap[m.keys[m.search(key)]]
+}
+
+// GetN 从key的位置开始顺时针查找，返回前n个不同的真实节点。
+// 同一个真实节点的多个虚拟节点只算一次，所以副本总是落在不同的机器上
+func (m *Map) GetN(key string, n int) []string {
+	if len(m.keys) == 0 || n <= 0 {
+		return nil
+	}
+	if n > len(m.weights) {
+		n = len(m.weights)
+	}
+	nodes := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	idx := m.search(key)
+	for i := 0; i < len(m.keys) && len(nodes) < n; i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// search 返回key顺时针方向第一个虚拟节点在 m.keys 中的下标，调用方保证环不为空
+func (m *Map) search(key string) int {
 	// 计算传入的key的哈希值
 	hash := int(m.hash([]byte(key)))
 	// 寻找第一个匹配的虚拟节点的下标
@@ -77,5 +253,65 @@ func (m *Map) Get(key string) string {
 	})
 	// 找到虚拟节点的在hash环中的下标后，还需要确定其hash值，再通过hashmap找真实节点
 	// keys切片中存储的是哈希环中的哈希值，因为这里的idx,可能会大于keys的长度，所以需要取余，得到真实的下标
-	return m.hashMap[m.keys[idx%len(m.keys)]]
+	return idx % len(m.keys)
+}
+
+/*
+*****************************有界负载*********************************
+调用方在向节点发请求前调用 Inc，请求结束后调用 Done，Map 据此记录每个节点正在处理的请求数。
+GetLeast 从key的位置开始顺时针查找，返回第一个负载还没有达到上限的节点，
+上限为 ceil((totalLoad+1) * (1+epsilon) * weight / totalWeight)，所以一定存在这样的节点。
+*/
+
+// GetLeast 与 Get 相同，但会跳过负载已达上限的节点。没有设置 epsilon 时等同于 Get
+func (m *Map) GetLeast(key string) string {
+	if len(m.keys) == 0 {
+		return ""
+	}
+	idx := m.search(key)
+	if m.epsilon <= 0 {
+		return m.hashMap[m.keys[idx]]
+	}
+	checked := make(map[string]bool)
+	for i := 0; i < len(m.keys) && len(checked) < len(m.weights); i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if checked[node] {
+			continue
+		}
+		checked[node] = true
+		if m.loads[node]+1 <= m.maxLoad(node) {
+			return node
+		}
+	}
+	// 正常情况下不会走到这里，保险起见返回原本的所有者
+	return m.hashMap[m.keys[idx]]
+}
+
+// maxLoad 返回节点在再多一个请求之后允许的最大负载
+func (m *Map) maxLoad(node string) int64 {
+	share := float64(m.weights[node]) / float64(m.totalWeight)
+	return int64(math.Ceil(float64(m.totalLoad+1) * (1 + m.epsilon) * share))
+}
+
+// Inc 记录节点上多了一个正在处理的请求，不存在的节点会被忽略
+func (m *Map) Inc(node string) {
+	if _, ok := m.weights[node]; !ok {
+		return
+	}
+	m.loads[node]++
+	m.totalLoad++
+}
+
+// Done 记录节点上的一个请求已经结束，与 Inc 成对调用。节点在请求期间被删除时会被忽略
+func (m *Map) Done(node string) {
+	if m.loads[node] <= 0 {
+		return
+	}
+	m.loads[node]--
+	m.totalLoad--
+}
+
+// Load 返回节点当前正在处理的请求数
+func (m *Map) Load(node string) int64 {
+	return m.loads[node]
 }
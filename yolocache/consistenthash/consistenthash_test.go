@@ -41,3 +41,136 @@ func TestHashing(t *testing.T) {
 	}
 
 }
+
+func TestRemove(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+
+	// 2, 4, 6, 8, 12, 14, 16, 18, 22, 24, 26, 28
+	hash.Add("6", "4", "2", "8")
+	// 删除 8 后剩下 2, 4, 6, 12, 14, 16, 22, 24, 26
+	hash.Remove("8")
+
+	testCases := map[string]string{
+		"2":  "2",
+		"11": "2",
+		"17": "2", // 原本属于 18
+		"23": "4",
+		"27": "2", // 原本属于 28
+	}
+	for k, v := range testCases {
+		if hash.Get(k) != v {
+			t.Errorf("Asking for %s, should have yielded %s", k, v)
+		}
+	}
+
+	hash.Remove("2", "4", "6")
+	if got := hash.Get("1"); got != "" {
+		t.Errorf("empty ring should yield \"\", got %s", got)
+	}
+}
+
+func TestAddWeighted(t *testing.T) {
+	hash := New(50, nil)
+	hash.Add("http://a")
+	hash.AddWeighted("http://b", 4)
+	if w := hash.Weight("http://b"); w != 4 {
+		t.Fatalf("Weight(http://b) = %d, want 4", w)
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[hash.Get("key"+strconv.Itoa(i))]++
+	}
+	// 权重为 1:4，b 分到的key大约是 a 的4倍；crc32 环本身的分布不太均匀，所以只检查一个宽松的范围
+	if ratio := float64(counts["http://b"]) / float64(counts["http://a"]); ratio < 2.5 || ratio > 6 {
+		t.Errorf("b/a = %d/%d, want roughly 4", counts["http://b"], counts["http://a"])
+	}
+
+	// 删除带权重的节点时，它所有的虚拟节点都要被移除
+	hash.Remove("http://b")
+	if len(hash.keys) != 50 || len(hash.hashMap) != 50 {
+		t.Fatalf("after Remove: %d keys, %d hashMap entries, want 50", len(hash.keys), len(hash.hashMap))
+	}
+	// 重新添加时更新权重而不是重复添加
+	hash.AddWeighted("http://a", 2)
+	if len(hash.keys) != 100 {
+		t.Fatalf("after reweight: %d keys, want 100", len(hash.keys))
+	}
+}
+
+func TestBoundedLoads(t *testing.T) {
+	hash := NewBounded(3, 0.25, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	// 2, 4, 6, 12, 14, 16, 22, 24, 26
+	hash.Add("6", "4", "2")
+
+	// 没有负载时与 Get 相同
+	if got := hash.GetLeast("11"); got != "2" {
+		t.Fatalf("GetLeast(11) = %s, want 2", got)
+	}
+	// 总负载为1时，每个节点的上限为 ceil(2 * 1.25 / 3) = 1，节点2已满，溢出到环上的下一个节点4
+	hash.Inc("2")
+	if got := hash.GetLeast("11"); got != "4" {
+		t.Fatalf("GetLeast(11) with 2 loaded = %s, want 4", got)
+	}
+	if got := hash.Get("11"); got != "2" {
+		t.Fatalf("Get should ignore loads, got %s", got)
+	}
+	hash.Done("2")
+	if got := hash.GetLeast("11"); got != "2" || hash.Load("2") != 0 {
+		t.Fatalf("after Done: GetLeast(11) = %s, load = %d", got, hash.Load("2"))
+	}
+
+	// 删除节点时它的负载也一并清除，之后的 Done 被忽略
+	hash.Inc("4")
+	hash.Remove("4")
+	hash.Done("4")
+	if hash.totalLoad != 0 {
+		t.Fatalf("totalLoad = %d, want 0", hash.totalLoad)
+	}
+}
+
+func TestCollision(t *testing.T) {
+	atoi := func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	}
+	// 节点2的虚拟节点为 02, 12, 22，节点12的虚拟节点为 012, 112, 212，两者在12处冲突
+	a := New(3, atoi)
+	a.Add("2", "12")
+	b := New(3, atoi)
+	b.Add("12")
+	b.Add("2")
+
+	// 无论添加顺序如何，名称较小的 "12" 占据冲突的位置
+	for _, m := range []*Map{a, b} {
+		if got := m.Get("11"); got != "12" {
+			t.Errorf("Get(11) = %s, want 12", got)
+		}
+		if m.Collisions() != 1 {
+			t.Errorf("Collisions = %d, want 1", m.Collisions())
+		}
+	}
+	if a.Digest() != b.Digest() {
+		t.Fatalf("rings built in different orders should have the same digest")
+	}
+
+	// 删除冲突的胜者后，落败的节点重新占据这个位置
+	a.Remove("12")
+	if got := a.Get("11"); got != "2" {
+		t.Errorf("after Remove(12): Get(11) = %s, want 2", got)
+	}
+	if a.Collisions() != 0 || len(a.keys) != 3 {
+		t.Errorf("after Remove(12): %d collisions, %d keys", a.Collisions(), len(a.keys))
+	}
+	c := New(3, atoi)
+	c.Add("2")
+	if a.Digest() != c.Digest() {
+		t.Errorf("digest should only depend on the current nodes")
+	}
+}
@@ -0,0 +1,101 @@
+package consistenthash
+
+import "sort"
+
+// Jump 实现 jump consistent hash（Lamping & Veach, 2014），把key映射到 [0, n) 中的一个桶，
+// 不需要虚拟节点，也几乎不占内存，分布非常均匀。
+// 它只能在末尾增删桶：桶按节点名称排序，名称排在最后的节点增删时只有 1/n 的key会移动，
+// 在中间增删节点会让后面所有桶的编号改变，移动的key要多得多。
+// 权重为 w 的节点占 w 个连续的桶
+type Jump struct {
+	weights map[string]int
+	buckets []string // 第i个桶属于哪个节点
+}
+
+func NewJump() *Jump {
+	return &Jump{weights: make(map[string]int)}
+}
+
+func (j *Jump) Add(nodes ...string) {
+	for _, node := range nodes {
+		j.weights[node] = 1
+	}
+	j.rebuild()
+}
+
+func (j *Jump) AddWeighted(node string, weight int) {
+	if weight <= 0 {
+		return
+	}
+	j.weights[node] = weight
+	j.rebuild()
+}
+
+func (j *Jump) Remove(nodes ...string) {
+	for _, node := range nodes {
+		delete(j.weights, node)
+	}
+	j.rebuild()
+}
+
+func (j *Jump) Weight(node string) int {
+	return j.weights[node]
+}
+
+func (j *Jump) Digest() uint64 {
+	return digestNodes("jump", j.weights)
+}
+
+// rebuild 按节点名称重新排列所有桶，保证所有进程得到相同的桶顺序
+func (j *Jump) rebuild() {
+	nodes := make([]string, 0, len(j.weights))
+	for node := range j.weights {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	j.buckets = j.buckets[:0]
+	for _, node := range nodes {
+		for i := 0; i < j.weights[node]; i++ {
+			j.buckets = append(j.buckets, node)
+		}
+	}
+}
+
+func (j *Jump) Get(key string) string {
+	if len(j.buckets) == 0 {
+		return ""
+	}
+	return j.buckets[jumpHash(hash64(key), len(j.buckets))]
+}
+
+// GetN 第一个节点与 Get 相同，之后从该桶开始依次向后查找其他节点的桶
+func (j *Jump) GetN(key string, n int) []string {
+	if len(j.buckets) == 0 || n <= 0 {
+		return nil
+	}
+	if n > len(j.weights) {
+		n = len(j.weights)
+	}
+	nodes := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	b := jumpHash(hash64(key), len(j.buckets))
+	for i := 0; i < len(j.buckets) && len(nodes) < n; i++ {
+		node := j.buckets[(b+i)%len(j.buckets)]
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// jumpHash 是论文中的原始算法：key 作为线性同余生成器的种子，每一步以 1/(b+1) 的概率"跳"到更大的桶
+func jumpHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
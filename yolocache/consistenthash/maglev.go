@@ -0,0 +1,149 @@
+package consistenthash
+
+import (
+	"sort"
+	"strconv"
+)
+
+// 默认的查找表大小，需要是质数，且远大于节点数（论文建议至少100倍）
+const defaultMaglevTableSize = 65537
+
+// Maglev 实现 Maglev 哈希（Eisenbud et al., 2016）：每个节点按自己的排列顺序轮流占据查找表中的空位，
+// 填满之后 Get 只需要查一次表。各节点占据的位置数最多相差1，分布非常均匀；
+// 节点变化时除了属于该节点的key之外，还会有少量其他key移动。
+// 权重为 w 的节点每一轮占据 w 个位置
+type Maglev struct {
+	size    uint64
+	weights map[string]int
+	table   []string // 查找表，table[hash(key) % size] 就是key的所有者
+}
+
+// NewMaglev 创建查找表大小为 size 的 Maglev，<= 0 时使用默认值 65537。
+// 查找表的大小必须是质数，节点的 skip 才能与它互质、遍历整张表，所以 size 不是质数时向上取到下一个质数
+func NewMaglev(size int) *Maglev {
+	if size <= 0 {
+		size = defaultMaglevTableSize
+	}
+	return &Maglev{size: nextPrime(uint64(size)), weights: make(map[string]int)}
+}
+
+// nextPrime 返回不小于 n 的最小质数，查找表不会太大，试除就够了
+func nextPrime(n uint64) uint64 {
+	if n <= 2 {
+		return 2
+	}
+	for ; ; n++ {
+		prime := true
+		for d := uint64(2); d*d <= n; d++ {
+			if n%d == 0 {
+				prime = false
+				break
+			}
+		}
+		if prime {
+			return n
+		}
+	}
+}
+
+func (m *Maglev) Add(nodes ...string) {
+	for _, node := range nodes {
+		m.weights[node] = 1
+	}
+	m.populate()
+}
+
+func (m *Maglev) AddWeighted(node string, weight int) {
+	if weight <= 0 {
+		return
+	}
+	m.weights[node] = weight
+	m.populate()
+}
+
+func (m *Maglev) Remove(nodes ...string) {
+	for _, node := range nodes {
+		delete(m.weights, node)
+	}
+	m.populate()
+}
+
+func (m *Maglev) Weight(node string) int {
+	return m.weights[node]
+}
+
+func (m *Maglev) Digest() uint64 {
+	return digestNodes("maglev/"+strconv.FormatUint(m.size, 10), m.weights)
+}
+
+// populate 按论文中的算法重新填充查找表。节点按名称排序，保证所有进程得到相同的表
+func (m *Maglev) populate() {
+	if len(m.weights) == 0 {
+		m.table = nil
+		return
+	}
+	nodes := make([]string, 0, len(m.weights))
+	for node := range m.weights {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	// 每个节点的排列为 (offset + j*skip) % size，skip 与 size 互质，所以能遍历整张表
+	offset := make([]uint64, len(nodes))
+	skip := make([]uint64, len(nodes))
+	next := make([]uint64, len(nodes))
+	for i, node := range nodes {
+		h := hash64(node)
+		offset[i] = h % m.size
+		skip[i] = mix64(h)%(m.size-1) + 1
+	}
+
+	table := make([]string, m.size)
+	filled := make([]bool, m.size)
+	var n uint64
+	for n < m.size {
+		for i, node := range nodes {
+			for w := 0; w < m.weights[node] && n < m.size; w++ {
+				// 找到该节点排列中下一个空位
+				c := (offset[i] + next[i]*skip[i]) % m.size
+				for filled[c] {
+					next[i]++
+					c = (offset[i] + next[i]*skip[i]) % m.size
+				}
+				table[c], filled[c] = node, true
+				next[i]++
+				n++
+			}
+		}
+	}
+	m.table = table
+}
+
+// GetN 第一个节点与 Get 相同，之后从该位置开始依次向后查找查找表中的其他节点。
+// 各节点在表中是交错分布的，所以很快就能找齐
+func (m *Maglev) GetN(key string, n int) []string {
+	if len(m.table) == 0 || n <= 0 {
+		return nil
+	}
+	if n > len(m.weights) {
+		n = len(m.weights)
+	}
+	nodes := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	idx := hash64(key) % m.size
+	for i := uint64(0); i < m.size && len(nodes) < n; i++ {
+		node := m.table[(idx+i)%m.size]
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func (m *Maglev) Get(key string) string {
+	if len(m.table) == 0 {
+		return ""
+	}
+	return m.table[hash64(key)%m.size]
+}
@@ -0,0 +1,88 @@
+package consistenthash
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+/*
+*****************************可替换的节点选择算法*********************************
+Map 是带虚拟节点的哈希环，虚拟节点较少时各节点分到的key并不均匀。
+Placement 把"根据key选择节点"抽象出来，除了哈希环之外还提供了：
+  - Rendezvous：最高随机权重（HRW）哈希，对每个节点算一个分数，分数最高的节点就是key的所有者
+  - Jump：Google 的 jump consistent hash，几乎不占内存，但只适合在末尾增删节点
+  - Maglev：Google Maglev 负载均衡器使用的查找表，分布最均匀，节点变化时会多移动少量key
+同一个集群中的所有节点必须使用同一种 Placement，否则各节点对key的所有者的判断会不一致。
+*/
+
+// Placement 根据key在一组带权重的节点中选出一个节点，实现不要求并发安全，由调用方加锁
+type Placement interface {
+	// Add 添加权重为1的节点，节点已存在时将权重重置为1
+	Add(nodes ...string)
+	// AddWeighted 添加带权重的节点，节点分到的key的数量大致与权重成正比，节点已存在时更新它的权重
+	AddWeighted(node string, weight int)
+	// Remove 删除节点，不存在的节点会被忽略
+	Remove(nodes ...string)
+	// Weight 返回节点的权重，节点不存在时返回0
+	Weight(node string) int
+	// Get 返回key所属的节点，没有节点时返回空字符串
+	Get(key string) string
+	// GetN 返回key的前n个不同的节点，第一个就是 Get 返回的节点，节点不足n个时返回所有节点
+	GetN(key string, n int) []string
+	// Digest 返回当前节点分布的摘要，两个 Placement 的摘要相同时，它们对所有key的选择都相同
+	Digest() uint64
+}
+
+// Bounded 是支持有界负载的 Placement，调用方在请求节点前后分别调用 Inc 和 Done 报告负载，
+// 再用 GetLeast 代替 Get 选择节点
+type Bounded interface {
+	Placement
+	GetLeast(key string) string
+	Inc(node string)
+	Done(node string)
+}
+
+var (
+	_ Placement = (*Map)(nil)
+	_ Bounded   = (*Map)(nil)
+	_ Placement = (*Rendezvous)(nil)
+	_ Placement = (*Jump)(nil)
+	_ Placement = (*Maglev)(nil)
+)
+
+// hash64 计算64位的哈希值。fnv 对只差一两个字符的输入（如 key1、key2）区分度不高，
+// 所以再经过一次 mix64 打散
+func hash64(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return mix64(h.Sum64())
+}
+
+// digestNodes 计算算法名称、参数与所有节点及其权重的摘要，适用于选择结果完全由这些决定的算法
+func digestNodes(kind string, weights map[string]int) uint64 {
+	nodes := make([]string, 0, len(weights))
+	for node := range weights {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	h := fnv.New64a()
+	h.Write([]byte(kind))
+	for _, node := range nodes {
+		h.Write([]byte{0})
+		h.Write([]byte(node))
+		h.Write([]byte{0})
+		h.Write([]byte(strconv.Itoa(weights[node])))
+	}
+	return h.Sum64()
+}
+
+// mix64 是 splitmix64 的最后一步，让输入的每一位都影响输出的每一位
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,206 @@
+package consistenthash
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+// 每个测试都会为所有实现各创建一个新的 Placement
+var placements = []struct {
+	name string
+	new  func() Placement
+}{
+	{"ring", func() Placement { return New(defaultTestReplicas, nil) }},
+	{"rendezvous", func() Placement { return NewRendezvous() }},
+	{"jump", func() Placement { return NewJump() }},
+	{"maglev", func() Placement { return NewMaglev(0) }},
+}
+
+const (
+	defaultTestReplicas = 50
+	testKeys            = 100000
+)
+
+func testNodes(n int) []string {
+	nodes := make([]string, n)
+	for i := range nodes {
+		nodes[i] = fmt.Sprintf("http://10.0.0.%d:8001", i+10)
+	}
+	return nodes
+}
+
+// owners 记录每个key的所有者
+func owners(p Placement) []string {
+	out := make([]string, testKeys)
+	for i := range out {
+		out[i] = p.Get("key" + strconv.Itoa(i))
+	}
+	return out
+}
+
+// skew 返回负载最高的节点分到的key数与平均值之比，1 表示完全均匀
+func skew(owners []string, nodes int) float64 {
+	counts := make(map[string]int)
+	max := 0
+	for _, o := range owners {
+		counts[o]++
+		if counts[o] > max {
+			max = counts[o]
+		}
+	}
+	return float64(max) / (float64(len(owners)) / float64(nodes))
+}
+
+func moved(before, after []string) float64 {
+	n := 0
+	for i := range before {
+		if before[i] != after[i] {
+			n++
+		}
+	}
+	return float64(n) / float64(len(before))
+}
+
+func TestPlacementSkew(t *testing.T) {
+	nodes := testNodes(10)
+	for _, pl := range placements {
+		p := pl.new()
+		p.Add(nodes...)
+		s := skew(owners(p), len(nodes))
+		t.Logf("%-10s skew %.3f", pl.name, s)
+		// 哈希环只有50个虚拟节点，分布明显不如其他三种均匀
+		limit := 1.1
+		if pl.name == "ring" {
+			limit = 1.6
+		}
+		if s > limit {
+			t.Errorf("%s: skew %.3f, want <= %.1f", pl.name, s, limit)
+		}
+	}
+}
+
+func TestPlacementMovement(t *testing.T) {
+	nodes := testNodes(10)
+	for _, pl := range placements {
+		p := pl.new()
+		p.Add(nodes...)
+		before := owners(p)
+
+		// 新节点的名称排在最后，对 Jump 来说就是在末尾追加一个桶；理想情况下移动 1/11 的key
+		p.Add("http://10.0.0.99:8001")
+		added := moved(before, owners(p))
+		p.Remove("http://10.0.0.99:8001")
+		if back := moved(before, owners(p)); back != 0 {
+			t.Errorf("%s: removing the new node should restore all owners, %.3f differ", pl.name, back)
+		}
+
+		// 删除中间的节点；理想情况下只有属于它的 1/10 的key移动
+		p.Remove(nodes[4])
+		removed := moved(before, owners(p))
+		t.Logf("%-10s moved on add %.3f, on remove %.3f", pl.name, added, removed)
+
+		if added > 0.2 {
+			t.Errorf("%s: adding a node moved %.3f of keys, want about 1/11", pl.name, added)
+		}
+		// Jump 在中间删除节点会改变后面所有桶的编号，这是该算法的已知限制，这里只记录不检查
+		if pl.name != "jump" && removed > 0.2 {
+			t.Errorf("%s: removing a node moved %.3f of keys, want about 1/10", pl.name, removed)
+		}
+	}
+}
+
+func TestPlacementWeighted(t *testing.T) {
+	for _, pl := range placements {
+		p := pl.new()
+		p.Add("http://a")
+		p.AddWeighted("http://b", 3)
+		if w := p.Weight("http://b"); w != 3 {
+			t.Fatalf("%s: Weight = %d, want 3", pl.name, w)
+		}
+		counts := make(map[string]int)
+		for _, o := range owners(p) {
+			counts[o]++
+		}
+		share := float64(counts["http://b"]) / testKeys
+		t.Logf("%-10s weight 3 of 4 gets %.3f", pl.name, share)
+		if share < 0.6 || share > 0.9 {
+			t.Errorf("%s: weight 3 of 4 got %.3f of keys, want about 0.75", pl.name, share)
+		}
+
+		p.Remove("http://a", "http://b")
+		if got := p.Get("key"); got != "" {
+			t.Errorf("%s: empty placement returned %q", pl.name, got)
+		}
+	}
+}
+
+func TestPlacementGetN(t *testing.T) {
+	nodes := testNodes(5)
+	for _, pl := range placements {
+		p := pl.new()
+		p.Add(nodes...)
+		for i := 0; i < 100; i++ {
+			key := "key" + strconv.Itoa(i)
+			got := p.GetN(key, 3)
+			if len(got) != 3 || got[0] != p.Get(key) {
+				t.Fatalf("%s: GetN(%s, 3) = %v, Get = %s", pl.name, key, got, p.Get(key))
+			}
+			if got[0] == got[1] || got[1] == got[2] || got[0] == got[2] {
+				t.Fatalf("%s: GetN(%s, 3) = %v, want distinct nodes", pl.name, key, got)
+			}
+		}
+		if got := p.GetN("key", 10); len(got) != len(nodes) {
+			t.Errorf("%s: GetN with n > nodes returned %d nodes, want %d", pl.name, len(got), len(nodes))
+		}
+		// 哈希环和 Rendezvous 中，删除所有者后原来的第二个副本成为新的所有者；
+		// Jump 和 Maglev 删除节点后会重新排列，不保证这一点
+		if pl.name != "ring" && pl.name != "rendezvous" {
+			continue
+		}
+		want := p.GetN("key", 2)
+		p.Remove(want[0])
+		if got := p.Get("key"); got != want[1] {
+			t.Errorf("%s: after removing %s, Get = %s, want %s", pl.name, want[0], got, want[1])
+		}
+	}
+}
+
+func TestPlacementDigest(t *testing.T) {
+	nodes := testNodes(5)
+	for _, pl := range placements {
+		a, b := pl.new(), pl.new()
+		a.Add(nodes...)
+		for i := len(nodes) - 1; i >= 0; i-- {
+			b.Add(nodes[i])
+		}
+		if a.Digest() != b.Digest() {
+			t.Errorf("%s: same nodes added in a different order should have the same digest", pl.name)
+		}
+		b.AddWeighted(nodes[0], 2)
+		if a.Digest() == b.Digest() {
+			t.Errorf("%s: changing a weight should change the digest", pl.name)
+		}
+	}
+}
+
+// 查找表的大小不是质数（或者太小）时会向上取到质数，否则 populate 会除零或者死循环
+func TestMaglevTableSize(t *testing.T) {
+	for _, tc := range []struct{ size, want int }{
+		{1, 2}, {2, 2}, {100, 101}, {1024, 1031}, {65537, 65537},
+	} {
+		m := NewMaglev(tc.size)
+		if int(m.size) != tc.want {
+			t.Fatalf("NewMaglev(%d).size = %d, want %d", tc.size, m.size, tc.want)
+		}
+		m.Add(testNodes(7)...)
+		if len(m.table) != tc.want {
+			t.Fatalf("size %d: table has %d slots, want %d", tc.size, len(m.table), tc.want)
+		}
+		for _, node := range m.table {
+			if node == "" {
+				t.Fatalf("size %d: table has an empty slot", tc.size)
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package consistenthash
+
+import (
+	"math"
+	"sort"
+)
+
+// Rendezvous 实现最高随机权重（HRW）哈希：对每个节点计算 hash(node, key) 得到一个分数，
+// 分数最高的节点就是key的所有者。删除节点时只有属于它的key会移动，添加节点时只有新节点"抢到"的key会移动。
+// 每次 Get 都要遍历所有节点，适合节点数不多（几十到几百个）的集群
+type Rendezvous struct {
+	nodes []rendezvousNode // 按名称排序，保证分数相同时的选择是确定的
+}
+
+type rendezvousNode struct {
+	name   string
+	hash   uint64
+	weight int
+}
+
+func NewRendezvous() *Rendezvous {
+	return &Rendezvous{}
+}
+
+func (r *Rendezvous) Add(nodes ...string) {
+	for _, node := range nodes {
+		r.AddWeighted(node, 1)
+	}
+}
+
+func (r *Rendezvous) AddWeighted(node string, weight int) {
+	if weight <= 0 {
+		return
+	}
+	i := r.index(node)
+	if i < len(r.nodes) && r.nodes[i].name == node {
+		r.nodes[i].weight = weight
+		return
+	}
+	r.nodes = append(r.nodes, rendezvousNode{})
+	copy(r.nodes[i+1:], r.nodes[i:])
+	r.nodes[i] = rendezvousNode{name: node, hash: hash64(node), weight: weight}
+}
+
+func (r *Rendezvous) Remove(nodes ...string) {
+	for _, node := range nodes {
+		if i := r.index(node); i < len(r.nodes) && r.nodes[i].name == node {
+			r.nodes = append(r.nodes[:i], r.nodes[i+1:]...)
+		}
+	}
+}
+
+func (r *Rendezvous) Weight(node string) int {
+	if i := r.index(node); i < len(r.nodes) && r.nodes[i].name == node {
+		return r.nodes[i].weight
+	}
+	return 0
+}
+
+func (r *Rendezvous) Digest() uint64 {
+	weights := make(map[string]int, len(r.nodes))
+	for _, n := range r.nodes {
+		weights[n.name] = n.weight
+	}
+	return digestNodes("rendezvous", weights)
+}
+
+// index 返回node在有序列表中的位置，不存在时返回应该插入的位置
+func (r *Rendezvous) index(node string) int {
+	return sort.Search(len(r.nodes), func(i int) bool { return r.nodes[i].name >= node })
+}
+
+// GetN 返回分数最高的n个节点，按分数从高到低排列
+func (r *Rendezvous) GetN(key string, n int) []string {
+	if len(r.nodes) == 0 || n <= 0 {
+		return nil
+	}
+	kh := hash64(key)
+	type scored struct {
+		name  string
+		score float64
+	}
+	all := make([]scored, len(r.nodes))
+	for i, node := range r.nodes {
+		all[i] = scored{node.name, node.score(kh)}
+	}
+	// 稳定排序，分数相同时保持名称顺序，与 Get 的选择一致
+	sort.SliceStable(all, func(i, j int) bool { return all[i].score > all[j].score })
+	if n > len(all) {
+		n = len(all)
+	}
+	nodes := make([]string, n)
+	for i := range nodes {
+		nodes[i] = all[i].name
+	}
+	return nodes
+}
+
+// Get 带权重的分数为 weight / -ln(u)，u 是由 hash(node, key) 得到的 (0,1) 之间的均匀随机数，
+// 这样每个节点成为所有者的概率正好与权重成正比
+func (r *Rendezvous) Get(key string) string {
+	if len(r.nodes) == 0 {
+		return ""
+	}
+	kh := hash64(key)
+	best, bestScore := -1, math.Inf(-1)
+	for i, n := range r.nodes {
+		if score := n.score(kh); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return r.nodes[best].name
+}
+
+// score 计算节点对哈希值为kh的key的分数
+func (n rendezvousNode) score(kh uint64) float64 {
+	// 取高53位作为浮点数的尾数，加0.5保证 u 不会等于0
+	u := (float64(mix64(n.hash^kh)>>11) + 0.5) / (1 << 53)
+	return float64(n.weight) / -math.Log(u)
+}
@@ -0,0 +1,78 @@
+package discovery
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+)
+
+/*
+***********************节点发现*********************************
+除了 gossip 之外，节点列表也可以来自部署环境：一个由运维维护的节点文件，或者一个 DNS 名称（A 记录或 SRV 记录）。
+Discovery 负责解析出当前的节点列表，Watch 定期重新解析，节点列表变化时同步给 HTTPPool。
+*/
+
+// Discovery 返回集群当前的节点列表，每个节点是 HTTPPool 使用的地址，如 http://10.0.0.1:8001
+type Discovery interface {
+	Resolve(ctx context.Context) ([]string, error)
+}
+
+// PeerSetter 接收最新的完整节点列表，*yolocache.HTTPPool 实现了这个接口
+type PeerSetter interface {
+	Set(peers ...string)
+}
+
+// Watch 立即解析一次节点列表，之后每隔 interval 重新解析，节点列表发生变化时调用 pool.Set。
+// 解析失败时只记录日志并保留原来的节点列表，避免数据源的短暂故障清空整个哈希环。
+// Watch 会一直运行直到 ctx 被取消，一般在单独的协程中调用
+func Watch(ctx context.Context, d Discovery, interval time.Duration, pool PeerSetter) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last []string
+	for {
+		peers, err := d.Resolve(ctx)
+		switch {
+		case err != nil:
+			log.Println("[Discovery] resolve:", err)
+		case len(peers) == 0:
+			log.Println("[Discovery] resolved no peers, keeping the current list")
+		default:
+			peers = normalize(peers)
+			if !equal(peers, last) {
+				log.Println("[Discovery] peers changed:", peers)
+				pool.Set(peers...)
+				last = peers
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// normalize 排序并去重，这样解析结果的顺序变化不会被当成节点变化
+func normalize(peers []string) []string {
+	sort.Strings(peers)
+	out := peers[:0]
+	for i, p := range peers {
+		if i == 0 || p != peers[i-1] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
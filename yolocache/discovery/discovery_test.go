@@ -0,0 +1,214 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakePool 记录最近一次 Set 的节点列表
+type fakePool struct {
+	mu    sync.Mutex
+	peers []string
+	sets  int
+}
+
+func (f *fakePool) Set(peers ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.peers = append([]string(nil), peers...)
+	f.sets++
+}
+
+func (f *fakePool) get() (string, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return fmt.Sprint(f.peers), f.sets
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers")
+	write := func(content string) {
+		// 先写临时文件再 rename，与文档中建议的做法一致
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("# cache nodes\nhttp://b:8001\n\nhttp://a:8001\nhttp://a:8001\n")
+
+	pool := &fakePool{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Watch(ctx, NewFile(path), 10*time.Millisecond, pool)
+
+	eventually(t, "initial peers", func() bool {
+		peers, _ := pool.get()
+		return peers == "[http://a:8001 http://b:8001]"
+	})
+	// 内容不变时不会重复调用 Set
+	time.Sleep(50 * time.Millisecond)
+	if _, sets := pool.get(); sets != 1 {
+		t.Fatalf("Set called %d times for an unchanged file", sets)
+	}
+
+	write("http://a:8001\nhttp://c:8001\n")
+	eventually(t, "updated peers", func() bool {
+		peers, _ := pool.get()
+		return peers == "[http://a:8001 http://c:8001]"
+	})
+
+	// 文件被删除时保留原来的节点列表
+	os.Remove(path)
+	time.Sleep(50 * time.Millisecond)
+	if peers, _ := pool.get(); peers != "[http://a:8001 http://c:8001]" {
+		t.Fatalf("peers changed after the file was removed: %s", peers)
+	}
+}
+
+// dnsStub 是一个只回答固定记录的DNS服务器，监听在回环地址上
+type dnsStub struct {
+	conn net.PacketConn
+	mu   sync.Mutex
+	a    map[string][]net.IP
+	srv  map[string][]net.SRV
+}
+
+func newDNSStub(t *testing.T) *dnsStub {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &dnsStub{conn: conn, a: make(map[string][]net.IP), srv: make(map[string][]net.SRV)}
+	t.Cleanup(func() { conn.Close() })
+	go s.serve()
+	return s
+}
+
+// resolver 返回只向 stub 查询的 net.Resolver
+func (s *dnsStub) resolver() *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "udp", s.conn.LocalAddr().String())
+		},
+	}
+}
+
+func (s *dnsStub) serve() {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if resp, err := s.answer(buf[:n]); err == nil {
+			s.conn.WriteTo(resp, addr)
+		}
+	}
+}
+
+func (s *dnsStub) answer(req []byte) ([]byte, error) {
+	var p dnsmessage.Parser
+	hdr, err := p.Start(req)
+	if err != nil {
+		return nil, err
+	}
+	q, err := p.Question()
+	if err != nil {
+		return nil, err
+	}
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: hdr.ID, Response: true, Authoritative: true})
+	b.EnableCompression()
+	b.StartQuestions()
+	b.Question(q)
+	b.StartAnswers()
+	rh := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 1}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := q.Name.String()
+	switch q.Type {
+	case dnsmessage.TypeA:
+		for _, ip := range s.a[name] {
+			var a [4]byte
+			copy(a[:], ip.To4())
+			b.AResource(rh, dnsmessage.AResource{A: a})
+		}
+	case dnsmessage.TypeSRV:
+		for _, srv := range s.srv[name] {
+			target := dnsmessage.MustNewName(srv.Target)
+			b.SRVResource(rh, dnsmessage.SRVResource{Priority: srv.Priority, Weight: srv.Weight, Port: srv.Port, Target: target})
+		}
+	}
+	// 其他类型（如AAAA）回答空的结果
+	return b.Finish()
+}
+
+func TestDNS(t *testing.T) {
+	stub := newDNSStub(t)
+	stub.mu.Lock()
+	stub.a["cache.test."] = []net.IP{net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.1")}
+	stub.mu.Unlock()
+
+	d := NewDNS("cache.test.", 8001)
+	d.Resolver = stub.resolver()
+	pool := &fakePool{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Watch(ctx, d, 10*time.Millisecond, pool)
+
+	eventually(t, "A records", func() bool {
+		peers, _ := pool.get()
+		return peers == "[http://10.0.0.1:8001 http://10.0.0.2:8001]"
+	})
+	stub.mu.Lock()
+	stub.a["cache.test."] = []net.IP{net.ParseIP("10.0.0.3")}
+	stub.mu.Unlock()
+	eventually(t, "re-resolved A records", func() bool {
+		peers, _ := pool.get()
+		return peers == "[http://10.0.0.3:8001]"
+	})
+}
+
+func TestDNSSRV(t *testing.T) {
+	stub := newDNSStub(t)
+	stub.mu.Lock()
+	stub.srv["_yolocache._tcp.test."] = []net.SRV{
+		{Target: "node1.test.", Port: 8001, Priority: 10, Weight: 1},
+		{Target: "node2.test.", Port: 8002, Priority: 10, Weight: 1},
+	}
+	stub.mu.Unlock()
+	d := NewDNSSRV("_yolocache._tcp.test.")
+	d.Resolver = stub.resolver()
+	peers, err := d.Resolve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	peers = normalize(peers)
+	if fmt.Sprint(peers) != "[http://node1.test:8001 http://node2.test:8002]" {
+		t.Fatalf("SRV peers = %v", peers)
+	}
+}
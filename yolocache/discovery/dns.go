@@ -0,0 +1,65 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// DNS 通过DNS解析节点列表：
+//   - SRV 为 false 时查询 Name 的 A/AAAA 记录，每个IP加上 Port 就是一个节点
+//   - SRV 为 true 时查询 Name 的 SRV 记录（如 _yolocache._tcp.example.com），每条记录的 target:port 就是一个节点
+//
+// 节点地址的格式为 Scheme://host:port，Scheme 默认为 http
+type DNS struct {
+	Name     string
+	Port     int  // 只在查询A记录时使用
+	SRV      bool // 查询SRV记录
+	Scheme   string
+	Resolver *net.Resolver // 为 nil 时使用 net.DefaultResolver
+}
+
+// NewDNS 创建查询A记录的 DNS，所有节点使用相同的端口
+func NewDNS(name string, port int) *DNS {
+	return &DNS{Name: name, Port: port}
+}
+
+// NewDNSSRV 创建查询SRV记录的 DNS，每个节点的端口来自SRV记录
+func NewDNSSRV(name string) *DNS {
+	return &DNS{Name: name, SRV: true}
+}
+
+func (d *DNS) Resolve(ctx context.Context) ([]string, error) {
+	r := d.Resolver
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	scheme := d.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	var peers []string
+	if d.SRV {
+		// service 和 proto 为空时直接查询 Name
+		_, srvs, err := r.LookupSRV(ctx, "", "", d.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, srv := range srvs {
+			host := strings.TrimSuffix(srv.Target, ".")
+			peers = append(peers, scheme+"://"+net.JoinHostPort(host, strconv.Itoa(int(srv.Port))))
+		}
+		return peers, nil
+	}
+	addrs, err := r.LookupHost(ctx, d.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		peers = append(peers, scheme+"://"+net.JoinHostPort(addr, strconv.Itoa(d.Port)))
+	}
+	return peers, nil
+}
+
+var _ Discovery = (*DNS)(nil)
@@ -0,0 +1,39 @@
+package discovery
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+)
+
+// File 从文件中读取节点列表，每行一个节点地址，空行和以 # 开头的行会被忽略。
+// 配合 Watch 使用时，每次都会重新读取文件，所以修改文件后最多一个 interval 就会生效；
+// 写文件时最好先写到临时文件再 rename，避免读到写了一半的内容
+type File struct {
+	Path string
+}
+
+func NewFile(path string) *File {
+	return &File{Path: path}
+}
+
+func (f *File) Resolve(ctx context.Context) ([]string, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var peers []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		peers = append(peers, line)
+	}
+	return peers, scanner.Err()
+}
+
+var _ Discovery = (*File)(nil)
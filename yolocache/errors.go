@@ -0,0 +1,85 @@
+package yolocache
+
+import (
+	pb "YoloCache/yolocache/yolocachepb"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+/*
+***********************节点间的错误协议*********************************
+所有者节点把加载的结果编码为 pb.Response 中的 code 和 error 字段，调用方再把它还原成下面的错误，
+这样调用方就能区分：
+  - 数据源中确实没有这个key（ErrNotFound）：所有者已经问过数据源了，调用方不应再回退到本地加载
+  - 所有者的回调函数出错（ErrGetterFailed）：同上，所有者已经尝试过，直接把错误返回给用户
+  - 所有者过载（ErrOverloaded）：所有者没有处理这个请求，调用方可以回退到本地加载
+  - 所有者的加载被取消或超时（ErrCanceled）：所有者没有得到结果，同上，调用方可以回退到本地加载
+*/
+
+var (
+	// ErrNotFound 表示数据源中不存在这个key，Getter 可以返回它（或包装了它的错误）来表明这种情况
+	ErrNotFound = errors.New("yolocache: key not found")
+	// ErrGetterFailed 表示所有者节点的回调函数返回了错误
+	ErrGetterFailed = errors.New("yolocache: getter failed on peer")
+	// ErrOverloaded 表示节点过载，暂时无法处理请求
+	ErrOverloaded = errors.New("yolocache: peer overloaded")
+	// ErrValueTooLarge 表示其他节点发来的值（解压后）超过了本节点允许的最大值，调用方会回退到本地加载。
+	// 这是值本身的问题，不算节点的失败，不会触发重试、熔断或摘除
+	ErrValueTooLarge = errors.New("yolocache: value exceeds the max value size")
+	// ErrCanceled 表示所有者节点的加载因为取消或超时而没有完成
+	ErrCanceled = errors.New("yolocache: load canceled on peer")
+	// ErrCircuitOpen 表示节点的熔断器处于打开状态，请求没有发出
+	ErrCircuitOpen = errors.New("yolocache: peer circuit breaker open")
+)
+
+// responseFromError 将服务端加载时遇到的错误编码为 pb.Response
+func responseFromError(err error) *pb.Response {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return &pb.Response{Code: pb.Code_NOT_FOUND, Error: err.Error()}
+	case errors.Is(err, ErrOverloaded):
+		return &pb.Response{Code: pb.Code_OVERLOADED, Error: err.Error()}
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// 取消和超时不是数据源给出的结果，不能让调用方当作所有者的确定结果
+		return &pb.Response{Code: pb.Code_CANCELED, Error: err.Error()}
+	default:
+		return &pb.Response{Code: pb.Code_GETTER_ERROR, Error: err.Error()}
+	}
+}
+
+// errorFromResponse 将 pb.Response 中的 code 还原为调用方的错误，code 为 OK 时返回 nil
+func errorFromResponse(res *pb.Response) error {
+	switch res.GetCode() {
+	case pb.Code_OK:
+		return nil
+	case pb.Code_NOT_FOUND:
+		return fmt.Errorf("%w: %s", ErrNotFound, res.GetError())
+	case pb.Code_OVERLOADED:
+		return fmt.Errorf("%w: %s", ErrOverloaded, res.GetError())
+	case pb.Code_CANCELED:
+		return fmt.Errorf("%w: %s", ErrCanceled, res.GetError())
+	default:
+		return fmt.Errorf("%w: %s", ErrGetterFailed, res.GetError())
+	}
+}
+
+// httpStatusForCode 返回 code 对应的HTTP状态码，方便在不解析 body 的情况下也能看出结果
+func httpStatusForCode(code pb.Code) int {
+	switch code {
+	case pb.Code_OK:
+		return http.StatusOK
+	case pb.Code_NOT_FOUND:
+		return http.StatusNotFound
+	case pb.Code_OVERLOADED, pb.Code_CANCELED:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// isAuthoritative 判断远程节点返回的错误是否是所有者给出的确定结果，是的话就不应再回退到本地加载
+func isAuthoritative(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, ErrGetterFailed)
+}
@@ -0,0 +1,395 @@
+package yolocache
+
+import (
+	"YoloCache/yolocache/consistenthash"
+	pb "YoloCache/yolocache/yolocachepb"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+/*
+***********************基于gRPC的节点间通信*********************************
+yolocachepb.proto 中定义的 GroupCache 服务，与 HTTPPool 的作用相同：
+GRPCPool 负责用一致性哈希选择节点，并持有到每个远程节点的 gRPC 连接（客户端）；
+grpcServer 把其他节点发来的 RPC 交给本地的 Group 处理（服务端）。
+与 HTTPPool 不同的是，这里的节点地址是 host:port，不带 http:// 前缀。
+*/
+
+// 每次RPC默认的超时时间，调用方的ctx没有设置更早的截止时间时生效
+const defaultRPCTimeout = 3 * time.Second
+
+type GRPCPool struct {
+	self        string // 自己的地址 host:port
+	mu          sync.Mutex
+	peers       *consistenthash.Map
+	grpcGetters map[string]*grpcGetter // 远程节点地址 -> 对应的gRPC客户端
+	timeout     time.Duration
+	dialOpts    []grpc.DialOption
+
+	version uint64 // 哈希环的版本号，每次 Set 加一
+	digest  uint64 // 哈希环的摘要，随每个转发的请求发给对方
+
+	compression compression // 节点间的压缩，默认不使用
+
+	maxValueSize int64 // 解压后允许的最大值
+}
+
+// GRPCPoolOption 用于在 NewGRPCPool 时配置 GRPCPool 的可选项
+type GRPCPoolOption func(*GRPCPool)
+
+// WithRPCTimeout 设置每次RPC的超时时间，0 表示只使用调用方ctx上的截止时间
+func WithRPCTimeout(d time.Duration) GRPCPoolOption {
+	return func(p *GRPCPool) {
+		p.timeout = d
+	}
+}
+
+// WithGRPCMaxValueSize 与 WithMaxValueSize 相同，用于 GRPCPool：限制解压后的值的大小，n <= 0 时使用默认的64MB。
+// 未压缩的值由 gRPC 自己的消息大小限制（grpc.MaxCallRecvMsgSize）约束
+func WithGRPCMaxValueSize(n int64) GRPCPoolOption {
+	return func(p *GRPCPool) {
+		if n > 0 {
+			p.maxValueSize = n
+		}
+	}
+}
+
+// WithDialOptions 追加建立gRPC连接时使用的选项，默认使用不加密的连接
+func WithDialOptions(opts ...grpc.DialOption) GRPCPoolOption {
+	return func(p *GRPCPool) {
+		p.dialOpts = append(p.dialOpts, opts...)
+	}
+}
+
+func NewGRPCPool(self string, opts ...GRPCPoolOption) *GRPCPool {
+	p := &GRPCPool{
+		self:     self,
+		timeout:  defaultRPCTimeout,
+		dialOpts: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+
+		maxValueSize: defaultMaxValueSize,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *GRPCPool) Log(format string, v ...interface{}) {
+	log.Printf("[gRPC Server %s] %s", p.self, fmt.Sprintf(format, v...))
+}
+
+// Set 重新设置所有节点。已经存在的节点会复用原来的连接，被移除的节点的连接会被关闭
+func (p *GRPCPool) Set(peers ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.peers = consistenthash.New(defaultReplicas, nil)
+	p.peers.Add(peers...)
+	p.version++
+	p.digest = p.peers.Digest()
+	getters := make(map[string]*grpcGetter, len(peers))
+	for _, peer := range peers {
+		if g, ok := p.grpcGetters[peer]; ok {
+			getters[peer] = g
+			delete(p.grpcGetters, peer)
+			continue
+		}
+		getters[peer] = &grpcGetter{
+			addr:     peer,
+			timeout:  p.timeout,
+			dialOpts: p.dialOpts,
+			latency:  peerLatencyHistogram(peer),
+			pool:     p,
+		}
+	}
+	// 剩下的就是被移除的节点
+	for _, g := range p.grpcGetters {
+		g.close()
+		releasePeerLatency(g.addr)
+	}
+	p.grpcGetters = getters
+}
+
+// PickPeer 根据key选择节点，选中的是其他节点时返回对应的gRPC客户端
+func (p *GRPCPool) PickPeer(key string) (PeerGetter, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil {
+		return nil, false
+	}
+	if peer := p.peers.Get(key); peer != "" && peer != p.self {
+		p.Log("pick peer %s", peer)
+		return p.grpcGetters[peer], true
+	}
+	return nil, false
+}
+
+var _ PeerPicker = (*GRPCPool)(nil)
+
+// ringInfo 返回随转发请求一起发送的哈希环版本号和摘要
+func (p *GRPCPool) ringInfo() (version, digest uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.version, p.digest
+}
+
+// Register 将 GroupCache 服务注册到调用方自己创建的 grpc.Server 上
+func (p *GRPCPool) Register(s *grpc.Server) {
+	pb.RegisterGroupCacheServer(s, &grpcServer{pool: p})
+}
+
+// Serve 在lis上启动gRPC服务，直到出错或lis被关闭才返回
+func (p *GRPCPool) Serve(lis net.Listener) error {
+	s := grpc.NewServer()
+	p.Register(s)
+	return s.Serve(lis)
+}
+
+/*
+***********************gRPC服务端*********************************
+ */
+
+type grpcServer struct {
+	pb.UnimplementedGroupCacheServer
+	pool *GRPCPool
+}
+
+func (s *grpcServer) group(name string) (*Group, error) {
+	g := GetGroup(name)
+	if g == nil {
+		return nil, status.Errorf(codes.NotFound, "no such group: %s", name)
+	}
+	return g, nil
+}
+
+func (s *grpcServer) Get(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	s.pool.Log("Get %s/%s", in.GetGroup(), in.GetKey())
+	g, err := s.group(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkHops(g, in.GetKey(), in.GetHops()); err != nil {
+		return nil, err
+	}
+	g.stats.serverRequests.Add(1)
+	s.checkRing(g, in.GetKey(), in.GetRingVersion(), in.GetRingDigest())
+	// 与 HTTPPool 相同，其他节点转发过来的请求只在本地加载
+	view, err := g.getForPeer(ctx, in.GetKey())
+	if err != nil {
+		// 调用方取消或超时的错误使用gRPC自己的状态码，加载本身的错误与HTTP一样编码在 pb.Response 中
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		return responseFromError(err), nil
+	}
+	res := &pb.Response{Value: view.b}
+	s.pool.compression.encode(res, in.GetAcceptEncoding())
+	return res, nil
+}
+
+// GetBatch 与 Get 相同，但一次处理多个key，每个key的结果分别编码在 pb.BatchResponse 中
+func (s *grpcServer) GetBatch(ctx context.Context, in *pb.BatchRequest) (*pb.BatchResponse, error) {
+	s.pool.Log("GetBatch %s [%d keys]", in.GetGroup(), len(in.GetKeys()))
+	g, err := s.group(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkHops(g, fmt.Sprintf("[%d keys]", len(in.GetKeys())), in.GetHops()); err != nil {
+		return nil, err
+	}
+	g.stats.serverRequests.Add(int64(len(in.GetKeys())))
+	s.checkRing(g, fmt.Sprintf("[%d keys]", len(in.GetKeys())), in.GetRingVersion(), in.GetRingDigest())
+	out := &pb.BatchResponse{Responses: g.getBatchForPeer(ctx, in.GetKeys())}
+	if ctx.Err() != nil {
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	for _, res := range out.Responses {
+		s.pool.compression.encode(res, in.GetAcceptEncoding())
+	}
+	return out, nil
+}
+
+// checkHops 与 HTTPPool 相同，拒绝转发次数过多的请求
+func (s *grpcServer) checkHops(g *Group, key string, hops uint32) error {
+	if err := checkHops(hops); err != nil {
+		s.pool.Log("%s/%s: %v", g.name, key, err)
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+// checkRing 与 HTTPPool.checkRing 相同，核对转发方的哈希环摘要
+func (s *grpcServer) checkRing(g *Group, key string, version, digest uint64) {
+	if _, local := s.pool.ringInfo(); digest != 0 && digest != local {
+		g.stats.ringMismatches.Add(1)
+		s.pool.Log("ring mismatch for %s/%s: sender digest %016x (version %d), local %016x",
+			g.name, key, digest, version, local)
+	}
+}
+
+// Set 和 Remove 是其他节点转发过来的写请求，本节点就是所有者，直接在本地处理
+func (s *grpcServer) Set(ctx context.Context, in *pb.SetRequest) (*pb.Response, error) {
+	g, err := s.group(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	g.setLocally(in.GetKey(), in.GetValue())
+	return &pb.Response{}, nil
+}
+
+func (s *grpcServer) Remove(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	g, err := s.group(in.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	g.removeLocally(in.GetKey())
+	return &pb.Response{}, nil
+}
+
+/*
+***********************gRPC客户端*********************************
+ */
+
+// grpcGetter 实现了PeerGetter，到同一个远程节点的所有请求复用一个连接
+type grpcGetter struct {
+	addr     string
+	timeout  time.Duration
+	dialOpts []grpc.DialOption
+	latency  *histogram
+	pool     *GRPCPool // 用于在请求中带上哈希环的版本号和摘要
+
+	mu     sync.Mutex
+	conn   *grpc.ClientConn
+	client pb.GroupCacheClient
+	closed bool // 节点已经被 Set 移除，调用方可能还拿着这个 grpcGetter，不能再为它建立连接
+}
+
+// errPeerRemoved 表示节点在请求发出前已经被移除，调用方会像其他节点错误一样回退到本地加载
+var errPeerRemoved = errors.New("yolocache: peer removed from pool")
+
+// getClient 第一次使用时才建立连接；grpc.Dial 不会阻塞，真正的连接在后台建立，断开后也会自动重连
+func (g *grpcGetter) getClient() (pb.GroupCacheClient, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.closed {
+		return nil, errPeerRemoved
+	}
+	if g.client != nil {
+		return g.client, nil
+	}
+	conn, err := grpc.Dial(g.addr, g.dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	g.conn = conn
+	g.client = pb.NewGroupCacheClient(conn)
+	return g.client, nil
+}
+
+// close 关闭连接，之后的请求直接失败，不会再重新建立连接
+func (g *grpcGetter) close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.closed = true
+	if g.conn != nil {
+		g.conn.Close()
+		g.conn, g.client = nil, nil
+	}
+}
+
+// callContext 为单次RPC加上超时，调用方ctx上更早的截止时间会优先生效
+func (g *grpcGetter) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if g.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, g.timeout)
+}
+
+func (g *grpcGetter) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	start := time.Now()
+	defer func() { g.latency.observe(time.Since(start)) }()
+	client, err := g.getClient()
+	if err != nil {
+		return err
+	}
+	in.RingVersion, in.RingDigest = g.pool.ringInfo()
+	in.AcceptEncoding = g.pool.compression.accept
+	ctx, cancel := g.callContext(ctx)
+	defer cancel()
+	res, err := client.Get(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = *res
+	if err := decodeResponse(out, g.pool.maxValueSize); err != nil {
+		return err
+	}
+	return errorFromResponse(out)
+}
+
+func (g *grpcGetter) Set(ctx context.Context, in *pb.SetRequest, out *pb.Response) error {
+	client, err := g.getClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := g.callContext(ctx)
+	defer cancel()
+	res, err := client.Set(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = *res
+	return nil
+}
+
+func (g *grpcGetter) Remove(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	client, err := g.getClient()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := g.callContext(ctx)
+	defer cancel()
+	res, err := client.Remove(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = *res
+	return nil
+}
+
+func (g *grpcGetter) GetBatch(ctx context.Context, in *pb.BatchRequest, out *pb.BatchResponse) error {
+	client, err := g.getClient()
+	if err != nil {
+		return err
+	}
+	in.RingVersion, in.RingDigest = g.pool.ringInfo()
+	in.AcceptEncoding = g.pool.compression.accept
+	ctx, cancel := g.callContext(ctx)
+	defer cancel()
+	res, err := client.GetBatch(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = *res
+	for _, r := range out.GetResponses() {
+		if err := decodeResponse(r, g.pool.maxValueSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	_ PeerGetter  = (*grpcGetter)(nil)
+	_ BatchGetter = (*grpcGetter)(nil)
+)
@@ -0,0 +1,181 @@
+package yolocache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+***********************节点的健康检查*********************************
+节点宕机后，一致性哈希仍然会选中它，每个请求都要先等一次失败的HTTP请求才能回退到本地加载。
+开启健康检查后：
+  - 被动检查：每次请求节点失败（网络错误或 5xx）都会被记录，连续失败 threshold 次的节点被暂时摘除
+  - 主动检查：后台每隔 interval 请求一次所有节点的 /_health，正常节点连续失败同样会被摘除，被摘除的节点恢复后重新参与选择
+两者都只在开启健康检查（interval > 0）时生效，否则请求失败不会被记录，节点也不会被摘除。
+被摘除的节点仍然是集群的成员，也仍然在哈希环上，只是选择节点时会被跳过，它的key由环上的下一个节点处理。
+所以 Set/Peers 等看到的节点列表、哈希环的版本号和摘要都不变，各节点的健康检查结果不同也不会被当成节点列表不一致。
+*/
+
+const defaultHealthThreshold = 3
+
+type healthConfig struct {
+	interval  time.Duration // 主动检查的间隔，0 表示不开启健康检查
+	threshold int           // 连续失败多少次后摘除节点
+}
+
+// WithHealthCheck 开启健康检查：每隔 interval 主动检查一次所有节点，节点连续失败 threshold 次后被暂时摘除，
+// 恢复后重新参与选择（摘除期间它一直在哈希环上）。threshold <= 0 时使用默认值3。开启后需要调用 Close 停止后台的检查。
+// interval <= 0 表示不开启健康检查，此时被动检查也不生效
+func WithHealthCheck(interval time.Duration, threshold int) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		if threshold <= 0 {
+			threshold = defaultHealthThreshold
+		}
+		p.health = healthConfig{interval: interval, threshold: threshold}
+	}
+}
+
+// peerHealth 是一个节点的健康状态，由 HTTPPool.mu 保护
+type peerHealth struct {
+	failures  int       // 连续失败的次数
+	ejected   bool      // 是否已被摘除
+	ejectedAt time.Time // 被摘除的时间
+	lastErr   string    // 最近一次失败的原因
+	lastCheck time.Time // 最近一次请求或检查的时间
+}
+
+// isEjected 对 nil 也可以调用，没有记录过的节点是健康的
+func (h *peerHealth) isEjected() bool {
+	return h != nil && h.ejected
+}
+
+// report 记录一次对节点的请求结果，err 为 nil 表示成功。没有开启健康检查时什么也不做
+func (p *HTTPPool) report(peer string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// 没有开启健康检查，或者节点已经被移除
+	if p.health.interval <= 0 || p.weights[peer] == 0 {
+		return
+	}
+	h := p.peerState[peer]
+	if h == nil {
+		h = &peerHealth{}
+		p.peerState[peer] = h
+	}
+	h.lastCheck = time.Now()
+	if err == nil {
+		h.failures = 0
+		if h.ejected {
+			// 节点恢复，重新参与选择
+			h.ejected = false
+			p.Log("peer %s recovered, selectable again", peer)
+		}
+		return
+	}
+	h.failures++
+	h.lastErr = err.Error()
+	if !h.ejected && h.failures >= p.health.threshold {
+		h.ejected = true
+		h.ejectedAt = time.Now()
+		p.Log("peer %s failed %d times (%s), ejected", peer, h.failures, h.lastErr)
+	}
+}
+
+// healthLoop 每隔 interval 检查一次所有节点，直到 Close
+func (p *HTTPPool) healthLoop() {
+	ticker := time.NewTicker(p.health.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.checkAll()
+		}
+	}
+}
+
+// checkAll 并发地检查所有其他节点，每个检查最多等待一个 interval
+func (p *HTTPPool) checkAll() {
+	p.mu.Lock()
+	peers := make([]string, 0, len(p.weights))
+	for peer := range p.weights {
+		if peer != p.self {
+			peers = append(peers, peer)
+		}
+	}
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.health.interval)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, peer := range peers {
+		wg.Add(1)
+		go func(peer string) {
+			defer wg.Done()
+			p.report(peer, p.checkPeer(ctx, peer))
+		}(peer)
+	}
+	wg.Wait()
+}
+
+// checkPeer 请求节点的 /_health，返回 200 以外的结果都算失败
+func (p *HTTPPool) checkPeer(ctx context.Context, peer string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, peer+p.basePath+healthPath, nil)
+	if err != nil {
+		return err
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	closeBody(res)
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check returned: %v", res.Status)
+	}
+	return nil
+}
+
+// peerStatusJSON 是节点健康状态接口的输出格式
+type peerStatusJSON struct {
+	Peer      string     `json:"peer"`
+	Weight    int        `json:"weight"`
+	Self      bool       `json:"self,omitempty"`
+	Ejected   bool       `json:"ejected"`
+	Failures  int        `json:"consecutive_failures"`
+	Breaker   string     `json:"breaker"` // 熔断器的状态：closed、open 或 half-open
+	LastError string     `json:"last_error,omitempty"`
+	LastCheck *time.Time `json:"last_check,omitempty"`
+	EjectedAt *time.Time `json:"ejected_at,omitempty"`
+}
+
+// servePeers 以JSON格式输出所有节点的健康状态，按地址排序
+func (p *HTTPPool) servePeers(w http.ResponseWriter) {
+	p.mu.Lock()
+	out := make([]peerStatusJSON, 0, len(p.weights))
+	for peer, weight := range p.weights {
+		s := peerStatusJSON{Peer: peer, Weight: weight, Self: peer == p.self}
+		s.Breaker = p.httpGetters[peer].breaker.current().String()
+		if h := p.peerState[peer]; h != nil {
+			s.Ejected, s.Failures, s.LastError = h.ejected, h.failures, h.lastErr
+			lastCheck := h.lastCheck
+			s.LastCheck = &lastCheck
+			if h.ejected {
+				ejectedAt := h.ejectedAt
+				s.EjectedAt = &ejectedAt
+			}
+		}
+		out = append(out, s)
+	}
+	p.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Peer < out[j].Peer })
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		p.Log("encoding peers: %v", err)
+	}
+}
@@ -3,14 +3,21 @@ package yolocache
 import (
 	"YoloCache/yolocache/consistenthash"
 	pb "YoloCache/yolocache/yolocachepb"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/golang/protobuf/proto"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 /*
@@ -27,6 +34,15 @@ const (
 	defaultBasePath = "/_yolocache/"
 	// 默认节点数
 	defaultReplicas = 50
+	// 统计信息的路径，不含 /，因此不会和 <group>/<key> 冲突
+	statsPath = "_stats"
+	// 哈希环信息的路径，用于节点之间核对哈希环是否一致
+	ringPath = "_ring"
+	// 健康检查和节点健康状态的路径
+	healthPath = "_health"
+	peersPath  = "_peers"
+	// 批量获取的路径，POST 一个 pb.BatchRequest
+	batchPath = "_batch"
 )
 
 type HTTPPool struct {
@@ -37,15 +53,107 @@ type HTTPPool struct {
 	// 多个 goroutine 同时添加/删除节点： 如果有一个 goroutine 正在添加或删除节点，而另一个 goroutine 同时也在修改节点信息，没有锁的话可能导致不一致的状态。
 	//
 	//并发的 HTTP 请求： 当有多个请求同时发生，它们可能会涉及到节点的增加、删除等操作，需要保证这些操作的原子性，避免竞态条件。
-	peers       *consistenthash.Map    // 一致性哈希算法的Map，用来根据具体的key选择节点
-	httpGetters map[string]*httpGetter // 映射远程节点与对应的 httpGetter。每一个远程节点对应一个 httpGetter，因为 httpGetter 与远程节点的地址 baseURL 有关
+	peers       consistenthash.Placement // 节点选择算法，用来根据具体的key选择节点，默认是一致性哈希环
+	httpGetters map[string]*httpGetter   // 映射远程节点与对应的 httpGetter。每一个远程节点对应一个 httpGetter，因为 httpGetter 与远程节点的地址 baseURL 有关
+	weights     map[string]int           // 集群中的所有节点及其权重，与 peers 中的节点相同
+
+	version uint64 // 哈希环的版本号，节点每变化一次加一
+	digest  uint64 // 哈希环的摘要，随版本号一起更新，随每个转发的请求发给对方
+
+	replicas int // 每个key的副本数，读请求在前一个副本失败时依次尝试后面的副本
+
+	inflight chan struct{} // 限制同时处理的Get请求数的信号量，nil 表示不限制
+
+	health    healthConfig
+	peerState map[string]*peerHealth // 节点地址 -> 健康状态
+	done      chan struct{}          // Close 时关闭，停止后台的健康检查
+	closeOnce sync.Once
+
+	timeout  time.Duration            // 请求其他节点的默认超时时间
+	timeouts map[string]time.Duration // 单独设置了超时时间的节点
+	retry    retryConfig
+	breaker  breakerConfig
+
+	client *http.Client // 请求其他节点使用的客户端，默认使用共享的 defaultTransport
+
+	streamThreshold int // 不小于这个大小的值使用流式传输，< 0 表示不使用
+
+	compression compression // 节点间的压缩，默认不使用
+
+	maxValueSize int64 // 从其他节点接受的最大值（流式传输的总长度、解压后的大小）
+}
+
+// HTTPPoolOption 用于在 NewHTTPPool 时配置 HTTPPool 的可选项
+type HTTPPoolOption func(*HTTPPool)
+
+// WithPlacement 替换默认的一致性哈希环，如 consistenthash.NewRendezvous()、NewJump()、NewMaglev(0)。
+// pl 应该是新创建的、还没有添加节点的实例，集群中所有节点必须使用同一种算法
+func WithPlacement(pl consistenthash.Placement) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.peers = pl
+	}
+}
+
+// WithBoundedLoads 使用有界负载的一致性哈希环：某个节点上正在进行的请求数超过平均值的 (1+epsilon) 倍时，
+// Get 请求会溢出到环上的下一个节点，避免热点key或不均匀的分布压垮单个节点。
+// 负载只统计本节点发出的请求，写请求（Set/Remove）总是发给真正的所有者
+func WithBoundedLoads(epsilon float64) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.peers = consistenthash.NewBounded(defaultReplicas, epsilon, nil)
+	}
+}
+
+// WithReplicas 设置每个key的副本数（包括所有者），所有者不可用时读请求会依次尝试其他副本，
+// 而不是每个节点都直接回退到数据源。默认为1，即只有所有者
+func WithReplicas(n int) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		if n > 0 {
+			p.replicas = n
+		}
+	}
 }
 
-func NewHTTPPool(self string) *HTTPPool {
-	return &HTTPPool{
+// WithMaxInflight 限制同时处理的来自其他节点的Get请求数，超过时直接回复过载，
+// 调用方收到后会回退到本地加载，而不是在这里排队。n <= 0 表示不限制
+func WithMaxInflight(n int) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		if n > 0 {
+			p.inflight = make(chan struct{}, n)
+		}
+	}
+}
+
+func NewHTTPPool(self string, opts ...HTTPPoolOption) *HTTPPool {
+	p := &HTTPPool{
 		self:     self,
 		basePath: defaultBasePath,
+		// 实例化一个一致性哈希算法， defaultReplicas是虚拟节点的倍数, nil表示使用默认的hash函数
+		peers:       consistenthash.New(defaultReplicas, nil),
+		httpGetters: make(map[string]*httpGetter),
+		weights:     make(map[string]int),
+		replicas:    1,
+		peerState:   make(map[string]*peerHealth),
+		done:        make(chan struct{}),
+		timeouts:    make(map[string]time.Duration),
+		client:      &http.Client{Transport: defaultTransport},
+
+		streamThreshold: defaultStreamThreshold,
+		maxValueSize:    defaultMaxValueSize,
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.digest = p.peers.Digest()
+	if p.health.interval > 0 {
+		go p.healthLoop()
+	}
+	return p
+}
+
+// Close 停止 HTTPPool 的后台任务（健康检查），不影响正在处理的请求
+func (p *HTTPPool) Close() error {
+	p.closeOnce.Do(func() { close(p.done) })
+	return nil
 }
 
 /*
@@ -65,6 +173,31 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		panic("HTTPPool serving unexpected path: " + r.URL.Path)
 	}
 	p.Log("%s %s", r.Method, r.URL.Path)
+	// /<basepath>/_stats 返回本节点所有Group的统计信息
+	if r.URL.Path == p.basePath+statsPath {
+		p.serveStats(w)
+		return
+	}
+	// /<basepath>/_health 供其他节点做健康检查
+	if r.URL.Path == p.basePath+healthPath {
+		w.Write([]byte("ok"))
+		return
+	}
+	// /<basepath>/_peers 返回所有节点的健康状态
+	if r.URL.Path == p.basePath+peersPath {
+		p.servePeers(w)
+		return
+	}
+	// /<basepath>/_ring 返回本节点的哈希环信息
+	if r.URL.Path == p.basePath+ringPath {
+		p.serveRing(w)
+		return
+	}
+	// /<basepath>/_batch 是其他节点发来的批量获取请求
+	if r.URL.Path == p.basePath+batchPath {
+		p.serveBatch(w, r)
+		return
+	}
 	// 请求url的格式： /<basepath>/<groupname>/<key>
 	// 分割字符串 第二个参数表示最多分割的次数
 	// 对Path前缀后的部分按照 / 进行分割，分成2 部分
@@ -83,9 +216,167 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "no such group: "+groupName, http.StatusNotFound)
 		return
 	}
-	// 根据key获取缓存值
-	view, err := group.Get(key) // RPC调用前的版本
-	body, err := proto.Marshal(&pb.Response{Value: view.ByteSlice()})
+	// PUT 和 DELETE 是其他节点转发过来的写请求，当前节点就是key的所有者，直接在本地处理，不再转发
+	switch r.Method {
+	case http.MethodPut:
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		in := &pb.SetRequest{}
+		if err = proto.Unmarshal(b, in); err != nil {
+			http.Error(w, "decoding request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		group.setLocally(key, in.GetValue())
+		w.Header().Set("Content-Type", "application/octet-stream")
+		return
+	case http.MethodDelete:
+		group.removeLocally(key)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		return
+	}
+	q := r.URL.Query()
+	hops, _ := strconv.ParseUint(q.Get("hops"), 10, 32)
+	if err := checkHops(uint32(hops)); err != nil {
+		p.Log("%s/%s: %v", groupName, key, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	group.stats.serverRequests.Add(1)
+	if !p.acquire() {
+		p.writeResponse(w, responseFromError(ErrOverloaded))
+		return
+	}
+	defer p.release()
+	version, _ := strconv.ParseUint(q.Get("ring_version"), 10, 64)
+	digest, _ := strconv.ParseUint(q.Get("ring_digest"), 16, 64)
+	p.checkRing(group, key, version, digest)
+	// 根据key获取缓存值, 使用请求自带的ctx, 调用方断开连接时本次加载也随之取消。
+	// 转发方已经认为本节点应该处理这个key，所以只在本地加载，不再转发，避免两个节点的节点列表不一致时来回转发
+	view, err := group.getForPeer(r.Context(), key) // RPC调用前的版本是 group.Get(key)
+	if err != nil {
+		// 加载失败时不能回复一个空的200，否则调用方会把空值当成真实的值
+		p.writeResponse(w, responseFromError(err))
+		return
+	}
+	res := &pb.Response{Value: view.b}
+	p.compression.encode(res, q["accept_encoding"])
+	// 大值分段直接写出，不再整个编码进 pb.Response，压缩编码放在响应头中
+	if p.streamThreshold >= 0 && len(res.Value) >= p.streamThreshold && acceptsStream(r) {
+		w.Header().Set("Content-Type", streamContentType)
+		if res.Encoding != "" {
+			w.Header().Set(streamEncodingHeader, res.Encoding)
+		}
+		if err := writeStream(w, res.Value); err != nil {
+			p.Log("streaming %s/%s: %v", groupName, key, err)
+		}
+		return
+	}
+	p.writeResponse(w, res)
+}
+
+// serveBatch 处理批量获取请求，每个key的结果（包括错误）分别编码在 pb.BatchResponse 中，
+// 所以只要请求本身是合法的，HTTP状态码总是200
+func (p *HTTPPool) serveBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	in := &pb.BatchRequest{}
+	if err = proto.Unmarshal(b, in); err != nil {
+		http.Error(w, "decoding request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	group := GetGroup(in.GetGroup())
+	if group == nil {
+		http.Error(w, "no such group: "+in.GetGroup(), http.StatusNotFound)
+		return
+	}
+	if err := checkHops(in.GetHops()); err != nil {
+		p.Log("%s [%d keys]: %v", in.GetGroup(), len(in.GetKeys()), err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	group.stats.serverRequests.Add(int64(len(in.GetKeys())))
+	p.checkRing(group, fmt.Sprintf("[%d keys]", len(in.GetKeys())), in.GetRingVersion(), in.GetRingDigest())
+	// 每个key和单独的Get一样占用一个名额，拿不到名额的key回复过载，调用方会逐个回退
+	out := &pb.BatchResponse{Responses: make([]*pb.Response, len(in.GetKeys()))}
+	var keys []string
+	var index []int // keys[j] 在 in.Keys 中的位置
+	for i, key := range in.GetKeys() {
+		if !p.acquire() {
+			out.Responses[i] = responseFromError(ErrOverloaded)
+			continue
+		}
+		defer p.release()
+		keys = append(keys, key)
+		index = append(index, i)
+	}
+	for j, res := range group.getBatchForPeer(r.Context(), keys) {
+		out.Responses[index[j]] = res
+	}
+	for _, res := range out.Responses {
+		p.compression.encode(res, in.GetAcceptEncoding())
+	}
+	p.writeProto(w, http.StatusOK, out)
+}
+
+// acquire 尝试占用一个处理Get请求的名额，没有限制时总是成功，成功后必须调用 release 归还
+func (p *HTTPPool) acquire() bool {
+	if p.inflight == nil {
+		return true
+	}
+	select {
+	case p.inflight <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release 归还 acquire 占用的名额
+func (p *HTTPPool) release() {
+	if p.inflight != nil {
+		<-p.inflight
+	}
+}
+
+// maxHops 是节点之间转发的请求最多经过的次数。收到转发请求的节点总是只在本地加载，
+// 所以合法的请求只会是1，或者是旧版本节点发来的0
+const maxHops = 1
+
+// checkHops 拒绝转发次数超过 maxHops 的请求，这说明某个节点把别人转发过来的请求又转发了出去
+func checkHops(hops uint32) error {
+	if hops > maxHops {
+		return fmt.Errorf("request has been forwarded %d times, at most %d allowed", hops, maxHops)
+	}
+	return nil
+}
+
+// checkRing 核对转发方的哈希环摘要，不一致时记录日志和计数。摘要为0表示对方没有发送（旧版本的节点）
+func (p *HTTPPool) checkRing(g *Group, key string, version, digest uint64) {
+	_, local := p.ringInfo()
+	if digest != 0 && digest != local {
+		g.stats.ringMismatches.Add(1)
+		p.Log("ring mismatch for %s/%s: sender digest %016x (version %d), local %016x", g.name, key, digest, version, local)
+	}
+}
+
+// writeResponse 将 pb.Response 编码后写入，HTTP状态码与 res.Code 对应
+func (p *HTTPPool) writeResponse(w http.ResponseWriter, res *pb.Response) {
+	p.writeProto(w, httpStatusForCode(res.GetCode()), res)
+}
+
+// writeProto 将 msg 编码后写入，调用方根据 Content-Type 判断 body 是不是 protobuf
+func (p *HTTPPool) writeProto(w http.ResponseWriter, code int, msg proto.Message) {
+	body, err := proto.Marshal(msg)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -93,11 +384,139 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// 将缓存值写入到ResponseWriter
 	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(code)
 	// 使用 w.Write() 将缓存值作为 httpResponse 的 body 返回。
 	//w.Write(view.ByteSlice())
 	w.Write(body)
 }
 
+// groupStatsJSON 是统计接口中每个Group的输出格式
+type groupStatsJSON struct {
+	Stats     Stats      `json:"stats"`
+	MainCache CacheStats `json:"main_cache"`
+	HotCache  CacheStats `json:"hot_cache"`
+	NegCache  CacheStats `json:"negative_cache"`
+}
+
+// serveStats 以JSON格式输出本节点所有Group的统计信息
+func (p *HTTPPool) serveStats(w http.ResponseWriter) {
+	out := make(map[string]groupStatsJSON)
+	for _, g := range allGroups() {
+		out[g.name] = groupStatsJSON{
+			Stats:     g.Stats(),
+			MainCache: g.CacheStats(MainCache),
+			HotCache:  g.CacheStats(HotCache),
+			NegCache:  g.CacheStats(NegativeCache),
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		p.Log("encoding stats: %v", err)
+	}
+}
+
+// ringJSON 是哈希环接口的输出格式
+type ringJSON struct {
+	Self    string         `json:"self"`
+	Version uint64         `json:"version"`
+	Digest  string         `json:"digest"`            // 十六进制的 Placement.Digest()
+	Peers   map[string]int `json:"peers"`             // 节点地址 -> 权重
+	Ejected []string       `json:"ejected,omitempty"` // 被健康检查暂时摘除的节点，它们仍在哈希环中，只是选择节点时被跳过
+}
+
+// ring 返回本节点当前的哈希环信息
+func (p *HTTPPool) ring() ringJSON {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := ringJSON{
+		Self:    p.self,
+		Version: p.version,
+		Digest:  fmt.Sprintf("%016x", p.digest),
+		Peers:   make(map[string]int, len(p.weights)),
+	}
+	for peer, weight := range p.weights {
+		out.Peers[peer] = weight
+		if p.peerState[peer].isEjected() {
+			out.Ejected = append(out.Ejected, peer)
+		}
+	}
+	sort.Strings(out.Ejected)
+	return out
+}
+
+func (p *HTTPPool) serveRing(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(p.ring()); err != nil {
+		p.Log("encoding ring: %v", err)
+	}
+}
+
+// Digest 返回本节点哈希环的摘要，所有节点的摘要相同时，它们对每个key的所有者的判断都一致
+func (p *HTTPPool) Digest() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.digest
+}
+
+// ringInfo 返回随转发请求一起发送的哈希环版本号和摘要
+func (p *HTTPPool) ringInfo() (version, digest uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.version, p.digest
+}
+
+// bumpLocked 在哈希环变化后更新版本号和摘要，调用时必须持有 p.mu
+func (p *HTTPPool) bumpLocked() {
+	p.version++
+	p.digest = p.peers.Digest()
+}
+
+// VerifyRing 向所有其他节点请求它们的哈希环摘要，与本节点比较。
+// 有节点的摘要不同或无法访问时返回错误，适合在节点开始对外服务之前调用
+func (p *HTTPPool) VerifyRing(ctx context.Context) error {
+	local := p.ring()
+	var problems []string
+	for peer := range local.Peers {
+		if peer == p.self {
+			continue
+		}
+		remote, err := p.fetchRing(ctx, peer+p.basePath+ringPath)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", peer, err))
+			continue
+		}
+		if remote.Digest != local.Digest {
+			problems = append(problems, fmt.Sprintf("%s: digest %s (version %d), local %s (version %d)",
+				peer, remote.Digest, remote.Version, local.Digest, local.Version))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("ring mismatch with %d peer(s): %s", len(problems), strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (p *HTTPPool) fetchRing(ctx context.Context, u string) (ringJSON, error) {
+	var out ringJSON
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return out, err
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return out, err
+	}
+	defer closeBody(res)
+	if res.StatusCode != http.StatusOK {
+		return out, fmt.Errorf("server returned: %v", res.Status)
+	}
+	if err = json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return out, fmt.Errorf("decoding ring: %v", err)
+	}
+	return out, nil
+}
+
 /*
 ***********************实现HTTP客户端*********************************
  */
@@ -105,37 +524,200 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 type httpGetter struct { // httpGetter实现了peerGetter的Get函数
 	// 表示将要访问的远程节点的地址
 	baseURL string
+	latency *histogram // 记录Get请求的耗时，用于输出监控指标
+
+	peer    string          // 远程节点的地址，不含 basePath
+	pool    *HTTPPool       // 用于在请求前后报告节点的负载
+	breaker *circuitBreaker // 节点的熔断器，nil 表示没有启用
 }
 
 // Get func (h *httpGetter) Get(group string, key string) ([]byte, error) {  RPC调用前的版本
-func (h *httpGetter) Get(in *pb.Request, out *pb.Response) error {
+func (h *httpGetter) Get(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	start := time.Now()
+	defer func() { h.latency.observe(time.Since(start)) }()
+	in.RingVersion, in.RingDigest = h.pool.ringInfo()
+	q := url.Values{}
+	q.Set("hops", strconv.FormatUint(uint64(in.GetHops()), 10))
+	q.Set("ring_version", strconv.FormatUint(in.GetRingVersion(), 10))
+	q.Set("ring_digest", strconv.FormatUint(in.GetRingDigest(), 16))
+	// 声明本节点能解压的编码，服务端据此决定是否压缩
+	in.AcceptEncoding = h.pool.compression.accept
+	for _, enc := range in.GetAcceptEncoding() {
+		q.Add("accept_encoding", enc)
+	}
+	return h.retrying(ctx, out, func() (bool, error) {
+		return h.do(ctx, http.MethodGet, in.GetGroup(), in.GetKey(), q, nil, out)
+	})
+}
+
+// GetBatch 使用 POST 请求，将 BatchRequest 发送给远程节点的 /<basepath>/_batch
+func (h *httpGetter) GetBatch(ctx context.Context, in *pb.BatchRequest, out *pb.BatchResponse) error {
+	in.RingVersion, in.RingDigest = h.pool.ringInfo()
+	in.AcceptEncoding = h.pool.compression.accept
+	body, err := proto.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("encoding request body: %v", err)
+	}
+	err = h.retrying(ctx, out, func() (bool, error) {
+		return h.send(ctx, http.MethodPost, h.baseURL+batchPath, body, out)
+	})
+	if err != nil {
+		return err
+	}
+	for _, res := range out.GetResponses() {
+		if err := decodeResponse(res, h.pool.maxValueSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retrying 调用 call，只用于幂等的请求：节点失败时退避一段随机时间后重试，每次重试前清空 out
+func (h *httpGetter) retrying(ctx context.Context, out proto.Message, call func() (failed bool, err error)) error {
+	retry := h.pool.retry
+	for attempt := 0; ; attempt++ {
+		failed, err := call()
+		if !failed || attempt+1 >= retry.attempts || !sleepContext(ctx, retry.wait(attempt)) {
+			return err
+		}
+		out.Reset()
+	}
+}
+
+// Set 使用 PUT 请求，将 SetRequest 作为 body 发送给远程节点
+func (h *httpGetter) Set(ctx context.Context, in *pb.SetRequest, out *pb.Response) error {
+	body, err := proto.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("encoding request body: %v", err)
+	}
+	_, err = h.do(ctx, http.MethodPut, in.GetGroup(), in.GetKey(), nil, body, out)
+	return err
+}
+
+// Remove 使用 DELETE 请求，删除远程节点中的缓存值
+func (h *httpGetter) Remove(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	_, err := h.do(ctx, http.MethodDelete, in.GetGroup(), in.GetKey(), nil, nil, out)
+	return err
+}
+
+// do 向远程节点的 /<basepath>/<group>/<key>?<query> 发送请求
+func (h *httpGetter) do(ctx context.Context, method, group, key string, query url.Values, body []byte, out *pb.Response) (failed bool, err error) {
 	u := fmt.Sprintf(
 		"%v%v/%v",
 		h.baseURL, // baseURL这里的最后一个字符是 /，所以不用再加了
-		url.QueryEscape(in.GetGroup()),
-		url.QueryEscape(in.GetKey()),
+		url.QueryEscape(group),
+		url.QueryEscape(key),
 	) //
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return h.send(ctx, method, u, body, out)
+}
+
+// send 向远程节点的 u 发送请求，并将返回的 body 解码到 out 中。
+// failed 表示错误是节点本身的问题（网络错误、超时、5xx），这样的请求可以重试；
+// 值超过 maxValueSize 是值本身的问题，不算节点的失败
+func (h *httpGetter) send(ctx context.Context, method, u string, body []byte, out proto.Message) (failed bool, err error) {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	// 熔断中的节点不发送请求，调用方会回退到其他副本或本地加载
+	if !h.breaker.allow() {
+		return false, fmt.Errorf("%w: %s", ErrCircuitOpen, h.peer)
+	}
+	// 请求期间该节点的负载加一，供有界负载的节点选择使用
+	defer h.pool.track(h.peer)()
+	// 记录这次请求是否成功，连续失败的节点会被熔断或暂时摘除；调用方自己取消的请求不算节点的失败
+	var failure error
+	defer func() {
+		if ctx.Err() != nil {
+			h.breaker.abandon()
+			return
+		}
+		h.breaker.record(failure)
+		h.pool.report(h.peer, failure)
+	}()
+	// 节点的超时时间只作用于这一次请求，超时算作节点的失败
+	reqCtx := ctx
+	if d := h.pool.timeoutFor(h.peer); d > 0 {
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
 	// TODO 与远程节点通信 可以考虑使用rpc
-	res, err := http.Get(u) // 向远程节点发送HTTP请求
+	// 使用带ctx的请求，调用方的超时和取消会中断这次HTTP通信
+	req, err := http.NewRequestWithContext(reqCtx, method, u, reqBody)
 	if err != nil {
-		return err
+		return false, err
 	}
-	defer res.Body.Close()
-	// 如果返回的状态码不是OK，就返回错误
-	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("server returned: %v", res.Status)
+	if method == http.MethodGet {
+		// 告诉对方可以流式传输大值，旧版本的节点会忽略它
+		req.Header.Set("Accept", streamContentType+", application/octet-stream")
+	}
+	res, err := h.pool.client.Do(req) // 向远程节点发送HTTP请求
+	if err != nil {
+		failure = err
+		return true, err
+	}
+	defer closeBody(res)
+	// 如果返回的状态码不是OK，且body不是 pb.Response（比如 http.Error 写的纯文本），就返回错误
+	if res.StatusCode != http.StatusOK && res.Header.Get("Content-Type") != "application/octet-stream" {
+		err = fmt.Errorf("server returned: %v", res.Status)
+		// 4xx（如没有这个Group）说明节点本身是正常的，只有 5xx 才算节点的失败
+		if res.StatusCode >= http.StatusInternalServerError {
+			failure = err
+		}
+		return failure != nil, err
+	}
+	// 流式传输的大值，按总长度一次分配好内存
+	if res.Header.Get("Content-Type") == streamContentType {
+		r, ok := out.(*pb.Response)
+		if !ok {
+			failure = fmt.Errorf("unexpected value stream for %s", u)
+			return true, failure
+		}
+		if r.Value, err = readStream(res.Body, h.pool.maxValueSize); err != nil {
+			if errors.Is(err, ErrValueTooLarge) {
+				return false, err
+			}
+			failure = fmt.Errorf("reading value stream: %v", err)
+			return true, failure
+		}
+		r.Encoding = res.Header.Get(streamEncodingHeader)
+		if err = decodeResponse(r, h.pool.maxValueSize); err != nil {
+			if errors.Is(err, ErrValueTooLarge) {
+				return false, err
+			}
+			failure = err
+			return true, failure
+		}
+		return false, nil
 	}
 	// 读取body
-	bytes, err := io.ReadAll(res.Body)
+	b, err := io.ReadAll(res.Body)
 
 	if err != nil {
-		return fmt.Errorf("reading response body: %v", err)
+		failure = fmt.Errorf("reading response body: %v", err)
+		return true, failure
 	}
 
-	if err = proto.Unmarshal(bytes, out); err != nil {
-		return fmt.Errorf("decoding response body: %v", err)
+	if err = proto.Unmarshal(b, out); err != nil {
+		failure = fmt.Errorf("decoding response body: %v", err)
+		return true, failure
 	}
-	return nil
+	// 远程节点通过 code 告知的错误，如不存在、回调函数失败、过载
+	if res, ok := out.(*pb.Response); ok {
+		if err = decodeResponse(res, h.pool.maxValueSize); err != nil {
+			if errors.Is(err, ErrValueTooLarge) {
+				return false, err
+			}
+			failure = err
+			return true, failure
+		}
+		return false, errorFromResponse(res)
+	}
+	return false, nil
 }
 
 // 表示创建了一个 *httpGetter 类型的 nil 值，并将其转换为 PeerGetter 接口类型。   类型断言：v.(ByteView)
@@ -151,41 +733,270 @@ func (h *httpGetter) Get(in *pb.Request, out *pb.Response) error {
 */
 // 接口健全性检查  由于接口的实现是隐式的，有时候我们希望在编译时就能够确保某个类型实现了某个接口，这样可以避免在运行时发生错误。
 // 将空值 nil 转换为 *httpGetter，再转换为 PeerGetter 接口，如果转换失败，说明 httpGetter 并没有实现 PeerGetter 接口的所有方法。
-var _ PeerGetter = (*httpGetter)(nil)
+var (
+	_ PeerGetter  = (*httpGetter)(nil)
+	_ BatchGetter = (*httpGetter)(nil)
+)
 
 // 为HTTPPool添加节点选择的功能
 
-// Set 方法实例化了一致性哈希算法，并且添加了传入的节点。
+// Set 方法将节点列表设置为传入的peers。
+// 与传入列表相比，新增的节点被加入哈希环，多出的节点被移除，已有节点的 httpGetter 和连接保持不变，
+// 所以也可以用它来整体同步一份最新的节点列表。
 // peers ...string 表示这里接受的peers是一个可变参数，可以传入0个或多个参数
 // s如果使用s...符号解压缩切片，则可以将切片直接传递给可变参数函数。在这种情况下，不会创建新的切片。
 func (p *HTTPPool) Set(peers ...string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	// 实例化一个一致性哈希算法， defaultReplicas是虚拟节点的倍数, nil表示使用默认的hash函数
-	p.peers = consistenthash.New(defaultReplicas, nil)
-	// 添加节点, 这里的peers...表示将切片peers打散传入
-	p.peers.Add(peers...)
-	// 初始化httpGetter， 每一个远程节点对应一个httpGetter，映射远程节点与对应的 httpGetter
-	p.httpGetters = make(map[string]*httpGetter, len(peers))
+	// 已有节点保留原来的权重，新节点的权重为1
+	want := make(map[string]int, len(peers))
+	for _, peer := range peers {
+		if w := p.weights[peer]; w > 0 {
+			want[peer] = w
+		} else {
+			want[peer] = 1
+		}
+	}
+	p.setLocked(want)
+}
+
+// SetWeighted 与 Set 相同，但同时指定每个节点的权重（节点地址 -> 权重），
+// 节点在哈希环上的虚拟节点数与权重成正比，容量大的机器可以分到更多的key。权重 <= 0 的节点会被忽略
+func (p *HTTPPool) SetWeighted(peers map[string]int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.setLocked(peers)
+}
+
+// setLocked 调用时必须持有 p.mu
+func (p *HTTPPool) setLocked(want map[string]int) {
+	var stale []string
+	for peer := range p.httpGetters {
+		if want[peer] <= 0 {
+			stale = append(stale, peer)
+		}
+	}
+	changed := p.removePeersLocked(stale)
+	for peer, weight := range want {
+		if p.addPeerLocked(peer, weight) {
+			changed = true
+		}
+	}
+	if changed {
+		p.bumpLocked()
+	}
+}
+
+// AddPeers 增量地添加节点，已经存在的节点会被忽略
+func (p *HTTPPool) AddPeers(peers ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.addPeersLocked(peers) {
+		p.bumpLocked()
+	}
+}
+
+// AddWeightedPeers 增量地添加带权重的节点（节点地址 -> 权重），已经存在的节点会更新为新的权重
+func (p *HTTPPool) AddWeightedPeers(peers map[string]int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	changed := false
+	for peer, weight := range peers {
+		if p.addPeerLocked(peer, weight) {
+			changed = true
+		}
+	}
+	if changed {
+		p.bumpLocked()
+	}
+}
+
+// RemovePeers 增量地移除节点，不存在的节点会被忽略
+func (p *HTTPPool) RemovePeers(peers ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.removePeersLocked(peers) {
+		p.bumpLocked()
+	}
+}
+
+// Version 返回哈希环的版本号，节点每变化一次加一，可以用来判断节点列表是否发生过变化
+func (p *HTTPPool) Version() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.version
+}
+
+// Peers 返回当前所有节点，按地址排序
+func (p *HTTPPool) Peers() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	peers := make([]string, 0, len(p.httpGetters))
+	for peer := range p.httpGetters {
+		peers = append(peers, peer)
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+// addPeersLocked 调用时必须持有 p.mu，返回是否真的添加了节点
+func (p *HTTPPool) addPeersLocked(peers []string) bool {
+	changed := false
 	for _, peer := range peers {
+		if _, ok := p.httpGetters[peer]; ok {
+			continue
+		}
+		if p.addPeerLocked(peer, 1) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// addPeerLocked 以给定的权重添加节点，节点已存在时只更新权重，调用时必须持有 p.mu。
+// 返回哈希环是否发生了变化
+func (p *HTTPPool) addPeerLocked(peer string, weight int) bool {
+	if weight <= 0 || p.weights[peer] == weight {
+		return false
+	}
+	p.weights[peer] = weight
+	p.peers.AddWeighted(peer, weight)
+	if _, ok := p.httpGetters[peer]; !ok {
 		// 为每一个远程节点创建一个httpGetter
-		p.httpGetters[peer] = &httpGetter{baseURL: peer + p.basePath}
+		p.httpGetters[peer] = &httpGetter{
+			baseURL: peer + p.basePath,
+			latency: peerLatencyHistogram(peer),
+			peer:    peer,
+			pool:    p,
+			breaker: newCircuitBreaker(p.breaker),
+		}
+	}
+	return true
+}
+
+// removePeersLocked 调用时必须持有 p.mu，返回是否真的移除了节点
+func (p *HTTPPool) removePeersLocked(peers []string) bool {
+	changed := false
+	for _, peer := range peers {
+		if _, ok := p.httpGetters[peer]; !ok {
+			continue
+		}
+		p.peers.Remove(peer)
+		delete(p.httpGetters, peer)
+		releasePeerLatency(peer)
+		delete(p.weights, peer)
+		delete(p.peerState, peer)
+		changed = true
 	}
+	return changed
 }
 
 // PickPeer PickerPeer() 包装一致性哈希算法的Get() 方法，并根据具体的key， 选择节点， 返回节点对应的http客户端
 func (p *HTTPPool) PickPeer(key string) (PeerGetter, bool) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	// 根据传入的key， 选择节点
 	// 如果选择的节点不是当前节点，那么就返回这个节点对应的http客户端
-	if peer := p.peers.Get(key); peer != "" && peer != p.self {
-		p.Log("pick peer %s", peer)
+	peer := p.pickLocked(key)
+	if peer != "" && peer != p.self {
 		// httpGetter实现了PeerGetter的Get方法，所以可以认为返回的httpGetter类型，就是PeerGetter类型
-		return p.httpGetters[peer], true // 返回节点对应的http客户端
+		g := p.httpGetters[peer]
+		// 熔断中的节点不会被选中，这个key暂时在本地加载
+		if !g.breaker.available() {
+			p.Log("peer %s circuit open, load locally", peer)
+			return nil, false
+		}
+		p.Log("pick peer %s", peer)
+		return g, true // 返回节点对应的http客户端
+	}
+	return nil, false
+}
+
+// pickLocked 根据传入的key选择节点，有界负载时会跳过负载已满的节点，结果可能是本节点。调用时必须持有 p.mu
+func (p *HTTPPool) pickLocked(key string) string {
+	if b, ok := p.peers.(consistenthash.Bounded); ok {
+		if peer := b.GetLeast(key); !p.peerState[peer].isEjected() {
+			return peer
+		}
+	}
+	return p.ownerLocked(key)
+}
+
+// ownerLocked 返回key的所有者，所有者被健康检查摘除时由环上的下一个节点代替。调用时必须持有 p.mu
+func (p *HTTPPool) ownerLocked(key string) string {
+	if peer := p.peers.Get(key); !p.peerState[peer].isEjected() {
+		return peer
+	}
+	for _, peer := range p.peers.GetN(key, len(p.weights)) {
+		if !p.peerState[peer].isEjected() {
+			return peer
+		}
+	}
+	return ""
+}
+
+// PickPeers 按顺序返回key的副本节点，第一个与 PickPeer 选中的节点相同，在本节点之前截止。
+// 熔断中的节点会被跳过，所有者熔断时由后面的副本处理
+func (p *HTTPPool) PickPeers(key string) []PeerGetter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	first := p.pickLocked(key)
+	if first == "" || first == p.self {
+		return nil
+	}
+	var peers []PeerGetter
+	if g := p.httpGetters[first]; g.breaker.available() {
+		peers = append(peers, g)
+	}
+	if p.replicas <= 1 {
+		return peers
+	}
+	// 被摘除的节点不算作副本，由环上后面的节点补上
+	n := 0
+	for _, peer := range p.peers.GetN(key, len(p.weights)) {
+		if p.peerState[peer].isEjected() {
+			continue
+		}
+		if peer == p.self || n == p.replicas {
+			break
+		}
+		n++
+		// 有界负载时第一个节点可能不是所有者，跳过已经在列表中的节点
+		if g := p.httpGetters[peer]; peer != first && g.breaker.available() {
+			peers = append(peers, g)
+		}
+	}
+	return peers
+}
+
+// PickOwner 返回key真正的所有者，不考虑负载，写请求使用它而不是 PickPeer
+func (p *HTTPPool) PickOwner(key string) (PeerGetter, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if peer := p.ownerLocked(key); peer != "" && peer != p.self {
+		return p.httpGetters[peer], true
 	}
 	return nil, false
 }
 
+// track 记录对节点的一次请求开始，返回的函数在请求结束时调用。只有 Bounded 的节点选择算法需要负载信息
+func (p *HTTPPool) track(peer string) (done func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b, ok := p.peers.(consistenthash.Bounded)
+	if !ok {
+		return func() {}
+	}
+	b.Inc(peer)
+	return func() {
+		p.mu.Lock()
+		b.Done(peer)
+		p.mu.Unlock()
+	}
+}
+
 // 编译时检查 HTTPPool 是否实现了 PeerPicker 接口
-var _ PeerPicker = (*HTTPPool)(nil)
+var (
+	_ PeerPicker    = (*HTTPPool)(nil)
+	_ OwnerPicker   = (*HTTPPool)(nil)
+	_ ReplicaPicker = (*HTTPPool)(nil)
+)
@@ -1,6 +1,9 @@
 package lru
 
-import "container/list"
+import (
+	"container/list"
+	"time"
+)
 
 /*
 ***********************LRU核心数据结构***************************
@@ -15,6 +18,7 @@ type Value interface {
 type Cache struct {
 	maxBytes int64                    // 允许使用的最大内存
 	nbytes   int64                    // 当前已使用的内存
+	nevict   int64                    // 因超出内存限制而被淘汰的记录数
 	ll       *list.List               // 双向链表, Go语言标准库实现
 	cache    map[string]*list.Element // 键是字符串, 值是双向链表中对应节点的指针
 	// value的类型是interface{}，可以接收任意类型的值
@@ -22,9 +26,16 @@ type Cache struct {
 }
 
 // entry 是双向链表的节点类型, 在链表中仍保存每个值对应的key的好处在于，淘汰队首节点时，需要用key从字典中删除对应的映射。
+// expire 为零值时表示该条记录永不过期
 type entry struct {
-	key   string
-	value Value
+	key    string
+	value  Value
+	expire time.Time
+}
+
+// expired 判断记录在now时刻是否已经过期
+func (e *entry) expired(now time.Time) bool {
+	return !e.expire.IsZero() && now.After(e.expire)
 }
 
 // Len 返回值所占用的内存大小
@@ -33,6 +44,16 @@ func (c *Cache) Len() int {
 	return c.ll.Len()
 }
 
+// Bytes 返回当前已使用的内存
+func (c *Cache) Bytes() int64 {
+	return c.nbytes
+}
+
+// Evictions 返回因超出内存限制而被淘汰的记录数，主动删除和过期删除不计入其中
+func (c *Cache) Evictions() int64 {
+	return c.nevict
+}
+
 // New 为了方便实例化Cache, 实现New()函数
 func New(maxBytes int64, onEvicted func(string, Value)) *Cache {
 	return &Cache{
@@ -71,6 +92,11 @@ func (c *Cache) Get(key string) (value Value, ok bool) {
 		*/
 		// 另一个更直观的原因就是 Add时，Push进去的是一个*entry类型的指针，所以这里取出来的时候也要取出来一个*entry类型的指针
 		kv := ele.Value.(*entry)
+		// 过期的记录视为未命中，并顺手删除（惰性删除）
+		if kv.expired(time.Now()) {
+			c.removeElement(ele)
+			return nil, false
+		}
 		return kv.value, true
 
 	}
@@ -86,14 +112,45 @@ func (c *Cache) Get(key string) (value Value, ok bool) {
 func (c *Cache) RemoveOldest() {
 	ele := c.ll.Back() // 双向链表的Back()方法返回队首节点
 	if ele != nil {
-		c.ll.Remove(ele) // 如果队首节点存在，则将其从双向链表中删除
-		// entry是结构体类型，所以这里要传的是指针，否则传的是值的话，只是传了一个副本，对副本的修改不会影响原来的值
-		kv := ele.Value.(*entry)
-		delete(c.cache, kv.key)                                // 从字典中删除对应的映射关系
-		c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len()) // 更新当前所用内存
-		if c.OnEvicted != nil {
-			c.OnEvicted(kv.key, kv.value) // 如果回调函数OnEvicted不为nil，则调用回调函数
+		c.removeElement(ele)
+		c.nevict++
+	}
+}
+
+// Remove 主动删除key对应的记录，返回记录是否存在
+func (c *Cache) Remove(key string) bool {
+	if ele, ok := c.cache[key]; ok {
+		c.removeElement(ele)
+		return true
+	}
+	return false
+}
+
+// RemoveExpired 遍历整个链表，删除所有已经过期的记录，返回删除的条数。
+// Get 只会惰性地删除被访问到的过期记录，没人访问的过期记录需要靠定期调用它来清理。
+func (c *Cache) RemoveExpired() int {
+	now := time.Now()
+	n := 0
+	for ele := c.ll.Back(); ele != nil; {
+		prev := ele.Prev() // 删除前先记下前一个节点，否则删除后就找不到了
+		if ele.Value.(*entry).expired(now) {
+			c.removeElement(ele)
+			n++
 		}
+		ele = prev
+	}
+	return n
+}
+
+// removeElement 将节点从链表和字典中删除，并更新已用内存
+func (c *Cache) removeElement(ele *list.Element) {
+	c.ll.Remove(ele) // 将其从双向链表中删除
+	// entry是结构体类型，所以这里要传的是指针，否则传的是值的话，只是传了一个副本，对副本的修改不会影响原来的值
+	kv := ele.Value.(*entry)
+	delete(c.cache, kv.key)                                // 从字典中删除对应的映射关系
+	c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len()) // 更新当前所用内存
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value) // 如果回调函数OnEvicted不为nil，则调用回调函数
 	}
 }
 
@@ -101,8 +158,13 @@ func (c *Cache) RemoveOldest() {
 **************************新增/修改功能***************************
  */
 
-// Add 新增/修改功能
+// Add 新增/修改功能, 记录永不过期
 func (c *Cache) Add(key string, value Value) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire 新增/修改功能, 记录在expire时刻之后过期，expire为零值表示永不过期
+func (c *Cache) AddWithExpire(key string, value Value, expire time.Time) {
 	if ele, ok := c.cache[key]; ok {
 		c.ll.MoveToFront(ele) // 如果键存在，则将对应节点移动到队尾
 		// 为什么这里传递的是指针？
@@ -110,11 +172,12 @@ func (c *Cache) Add(key string, value Value) {
 		kv := ele.Value.(*entry)
 		//更新nbytes, 因为新值可能和旧值大小不同
 		c.nbytes += int64(value.Len()) - int64(kv.value.Len())
-		// 更新节点值
+		// 更新节点值和过期时间
 		kv.value = value
+		kv.expire = expire
 	} else {
 		// 如果不存在，则在队尾添加新节点，并在字典中添加key和节点的映射关系
-		ele := c.ll.PushFront(&entry{key, value})
+		ele := c.ll.PushFront(&entry{key, value, expire})
 		// 在字典中添加key和节点的映射关系
 		c.cache[key] = ele
 		// 更新当前所用内存
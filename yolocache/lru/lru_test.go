@@ -3,6 +3,7 @@ package lru
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 // 单元测试
@@ -77,3 +78,37 @@ func TestOnevicted(t *testing.T) {
 	}
 
 }
+
+// 测试过期的记录在Get时被当作未命中并删除，以及RemoveExpired能清理未被访问的过期记录
+func TestExpire(t *testing.T) {
+	lru := New(int64(0), nil)
+	lru.AddWithExpire("key1", String("1234"), time.Now().Add(-time.Second))
+	lru.AddWithExpire("key2", String("5678"), time.Now().Add(time.Hour))
+	lru.AddWithExpire("key3", String("9"), time.Now().Add(-time.Second))
+	lru.Add("key4", String("0"))
+
+	if _, ok := lru.Get("key1"); ok {
+		t.Fatalf("expired key1 should miss")
+	}
+	if lru.Len() != 3 {
+		t.Fatalf("expired key1 should be removed on Get, len = %d", lru.Len())
+	}
+	if n := lru.RemoveExpired(); n != 1 || lru.Len() != 2 {
+		t.Fatalf("RemoveExpired removed %d, len = %d", n, lru.Len())
+	}
+	if v, ok := lru.Get("key2"); !ok || string(v.(String)) != "5678" {
+		t.Fatalf("cache hit key2=5678 failed")
+	}
+}
+
+// 测试只有因超出内存限制而被淘汰的记录才计入Evictions
+func TestEvictions(t *testing.T) {
+	lru := New(int64(len("k1"+"v1"+"k2"+"v2")), nil)
+	lru.Add("k1", String("v1"))
+	lru.Add("k2", String("v2"))
+	lru.Add("k3", String("v3"))
+	lru.Remove("k2")
+	if lru.Evictions() != 1 || lru.Len() != 1 || lru.Bytes() != int64(len("k3"+"v3")) {
+		t.Fatalf("evictions = %d, len = %d, bytes = %d", lru.Evictions(), lru.Len(), lru.Bytes())
+	}
+}
@@ -0,0 +1,507 @@
+package membership
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+***********************基于gossip的成员管理（SWIM）*********************************
+静态的节点列表需要在每个节点上手动维护，节点宕机后也不会被移出哈希环。
+Memberlist 实现了 SWIM 协议的一个简化版本，节点之间通过 UDP 互相发现并检测故障：
+  - 加入：新节点向一个或多个种子节点发送 join，种子回复自己知道的所有成员，新节点的 alive 消息随后被 gossip 给所有节点
+  - 故障检测：每个周期随机探测一个成员（ping），超时未收到 ack 时请其他 k 个成员代为探测（ping-req），
+    仍然失败就把它标记为 suspect；suspect 超过一段时间没有被推翻就标记为 dead，从哈希环中移除
+  - 反驳：节点得知自己被怀疑时，把自己的 incarnation 加一后广播 alive，推翻旧的 suspect 消息
+  - 离开：节点主动广播 left 后退出，其他节点立即把它移出哈希环
+成员状态的变化不单独发送，而是捎带（piggyback）在 ping/ack 等消息上传播。
+*/
+
+// State 表示成员的状态
+type State int
+
+const (
+	StateAlive   State = iota // 正常
+	StateSuspect              // 探测失败，怀疑已经宕机，但仍然留在哈希环中
+	StateDead                 // 确认宕机
+	StateLeft                 // 主动离开
+)
+
+func (s State) String() string {
+	switch s {
+	case StateAlive:
+		return "alive"
+	case StateSuspect:
+		return "suspect"
+	case StateDead:
+		return "dead"
+	case StateLeft:
+		return "left"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// down 表示成员已经不在集群中
+func (s State) down() bool {
+	return s == StateDead || s == StateLeft
+}
+
+// Member 是集群中的一个成员
+type Member struct {
+	Name        string `json:"name"`  // 成员在缓存集群中的地址，如 http://localhost:8001，会被交给 PeerSet
+	Addr        string `json:"addr"`  // 成员 gossip 使用的 UDP 地址
+	State       State  `json:"state"` // 成员的状态
+	Incarnation uint64 `json:"inc"`   // 成员自己维护的版本号，数值大的消息覆盖数值小的消息
+}
+
+// PeerSet 接收成员的变化，*yolocache.HTTPPool 实现了这个接口。
+// 成员变为 alive 时调用 AddPeers，变为 dead 或 left 时调用 RemovePeers；它的方法不能反过来调用 Memberlist
+type PeerSet interface {
+	AddPeers(peers ...string)
+	RemovePeers(peers ...string)
+}
+
+// Config 是 Memberlist 的配置，除了 Name 和 BindAddr 之外都有默认值
+type Config struct {
+	Name     string  // 本节点在缓存集群中的地址
+	BindAddr string  // gossip 监听的 UDP 地址，如 127.0.0.1:7946；端口为0时由系统分配。其他节点会用它来访问本节点，所以不能是 0.0.0.0
+	Peers    PeerSet // 成员变化时通知的对象，可以为 nil
+
+	ProbeInterval  time.Duration // 每隔多久探测一个成员，默认 1s
+	ProbeTimeout   time.Duration // 等待 ack 的时间，直接探测和间接探测各等待这么久，默认 300ms
+	SuspectTimeout time.Duration // suspect 多久没有被推翻就认为成员已经宕机，默认 5s
+	IndirectChecks int           // 直接探测失败后请多少个成员代为探测，默认 3
+
+	Logf func(format string, v ...interface{}) // 输出日志的函数，默认为 log.Printf
+}
+
+const (
+	defaultProbeInterval  = time.Second
+	defaultProbeTimeout   = 300 * time.Millisecond
+	defaultSuspectTimeout = 5 * time.Second
+	defaultIndirectChecks = 3
+
+	maxPiggyback   = 8     // 每条消息最多捎带的状态变化数
+	retransmitMult = 4     // 每个状态变化最多被捎带 retransmitMult * ceil(log10(n+1)) 次
+	maxPacketSize  = 65507 // UDP 数据报的最大长度
+)
+
+// Memberlist 维护集群的成员列表，通过 New 创建，Close 或 Leave 后不能再使用
+type Memberlist struct {
+	cfg  Config
+	conn *net.UDPConn
+	addr string // 实际监听的地址
+
+	mu      sync.Mutex
+	members map[string]*member // 成员名称 -> 成员，包括自己和已经宕机的成员
+	queue   map[string]*broadcast
+	seq     uint64
+	acks    map[uint64]func(message) // 等待回复的消息序号 -> 收到回复时的回调
+	probes  []string                 // 本轮探测的顺序，每轮开始时打乱
+	logf    func(format string, v ...interface{})
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+type member struct {
+	Member
+	suspectAt time.Time // 被标记为 suspect 的时间
+}
+
+// broadcast 是一条等待被捎带出去的状态变化
+type broadcast struct {
+	update    Member
+	transmits int
+}
+
+// New 在 cfg.BindAddr 上开始监听并启动故障检测，此时集群中只有自己，调用 Join 加入已有的集群
+func New(cfg Config) (*Memberlist, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("membership: Name is required")
+	}
+	if cfg.ProbeInterval <= 0 {
+		cfg.ProbeInterval = defaultProbeInterval
+	}
+	if cfg.ProbeTimeout <= 0 {
+		cfg.ProbeTimeout = defaultProbeTimeout
+	}
+	if cfg.SuspectTimeout <= 0 {
+		cfg.SuspectTimeout = defaultSuspectTimeout
+	}
+	if cfg.IndirectChecks <= 0 {
+		cfg.IndirectChecks = defaultIndirectChecks
+	}
+	if cfg.Logf == nil {
+		cfg.Logf = log.Printf
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", cfg.BindAddr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, err
+	}
+	m := &Memberlist{
+		cfg:     cfg,
+		conn:    conn,
+		addr:    conn.LocalAddr().String(),
+		members: make(map[string]*member),
+		queue:   make(map[string]*broadcast),
+		acks:    make(map[uint64]func(message)),
+		done:    make(chan struct{}),
+		logf:    cfg.Logf,
+	}
+	// incarnation 从当前时间开始，节点重启后的 alive 消息就能覆盖其他节点记住的 dead 状态
+	self := Member{Name: cfg.Name, Addr: m.addr, State: StateAlive, Incarnation: uint64(time.Now().UnixNano())}
+	m.members[cfg.Name] = &member{Member: self}
+	m.enqueue(self)
+	if cfg.Peers != nil {
+		cfg.Peers.AddPeers(cfg.Name)
+	}
+
+	m.wg.Add(2)
+	go m.readLoop()
+	go m.probeLoop()
+	return m, nil
+}
+
+// Addr 返回 gossip 实际监听的地址，BindAddr 的端口为0时可以用它得到系统分配的端口
+func (m *Memberlist) Addr() string {
+	return m.addr
+}
+
+// Join 向种子节点发送 join，并等待它们回复成员列表。返回回复了的种子数，一个都没有回复时返回错误
+func (m *Memberlist) Join(seeds ...string) (int, error) {
+	replies := make(chan struct{}, len(seeds))
+	var seqs []uint64
+	for _, seed := range seeds {
+		seq := m.expect(func(message) { replies <- struct{}{} })
+		seqs = append(seqs, seq)
+		if err := m.send(seed, message{Type: msgJoin, Seq: seq, Updates: []Member{m.self()}}); err != nil {
+			m.logf("[Membership %s] join %s: %v", m.cfg.Name, seed, err)
+		}
+	}
+	defer func() {
+		for _, seq := range seqs {
+			m.forget(seq)
+		}
+	}()
+
+	n := 0
+	timeout := time.After(2 * m.cfg.ProbeTimeout)
+	for n < len(seeds) {
+		select {
+		case <-replies:
+			n++
+		case <-timeout:
+			if n == 0 && len(seeds) > 0 {
+				return 0, fmt.Errorf("membership: no seed responded: %v", seeds)
+			}
+			return n, nil
+		case <-m.done:
+			return n, errors.New("membership: closed")
+		}
+	}
+	return n, nil
+}
+
+// Members 返回当前所有 alive 和 suspect 的成员（包括自己），按名称排序
+func (m *Memberlist) Members() []Member {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Member, 0, len(m.members))
+	for _, mem := range m.members {
+		if !mem.State.down() {
+			out = append(out, mem.Member)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Leave 通知所有成员本节点将要离开，然后关闭 Memberlist
+func (m *Memberlist) Leave() error {
+	m.mu.Lock()
+	self := m.members[m.cfg.Name]
+	self.Incarnation++
+	self.State = StateLeft
+	left := self.Member
+	var addrs []string
+	for _, mem := range m.members {
+		if mem.Name != m.cfg.Name && !mem.State.down() {
+			addrs = append(addrs, mem.Addr)
+		}
+	}
+	m.mu.Unlock()
+	// 离开后就不再参与gossip了，所以直接发给每个成员，而不是等待捎带
+	for _, addr := range addrs {
+		if err := m.send(addr, message{Type: msgAlive, Updates: []Member{left}}); err != nil {
+			m.logf("[Membership %s] leave %s: %v", m.cfg.Name, addr, err)
+		}
+	}
+	return m.Close()
+}
+
+// Close 停止 gossip 和故障检测，不通知其他成员，其他成员会通过故障检测发现本节点已经宕机
+func (m *Memberlist) Close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		close(m.done)
+		err = m.conn.Close()
+		m.wg.Wait()
+	})
+	return err
+}
+
+func (m *Memberlist) self() Member {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.members[m.cfg.Name].Member
+}
+
+/*
+***********************成员状态的合并*********************************
+ */
+
+// apply 合并一条成员状态的变化，返回是否产生了变化，调用时必须持有 m.mu。
+// 同一个成员的消息按 incarnation 排序，incarnation 相同时 dead/left 覆盖 suspect，suspect 覆盖 alive
+func (m *Memberlist) apply(u Member) bool {
+	if u.Name == m.cfg.Name {
+		return m.refute(u)
+	}
+	cur, ok := m.members[u.Name]
+	if !ok {
+		m.members[u.Name] = &member{Member: u, suspectAt: time.Now()}
+		m.enqueue(u)
+		if !u.State.down() {
+			m.logf("[Membership %s] %s joined (%s)", m.cfg.Name, u.Name, u.State)
+			m.notify(u.Name, true)
+		}
+		return true
+	}
+	switch u.State {
+	case StateAlive:
+		if u.Incarnation <= cur.Incarnation {
+			return false
+		}
+		if cur.State.down() {
+			m.logf("[Membership %s] %s rejoined", m.cfg.Name, u.Name)
+			m.notify(u.Name, true)
+		}
+	case StateSuspect:
+		if cur.State.down() || u.Incarnation < cur.Incarnation ||
+			(u.Incarnation == cur.Incarnation && cur.State == StateSuspect) {
+			return false
+		}
+		m.logf("[Membership %s] suspect %s", m.cfg.Name, u.Name)
+		cur.suspectAt = time.Now()
+	case StateDead, StateLeft:
+		if cur.State.down() || u.Incarnation < cur.Incarnation {
+			return false
+		}
+		m.logf("[Membership %s] %s is %s", m.cfg.Name, u.Name, u.State)
+		m.notify(u.Name, false)
+	}
+	cur.Member = u
+	m.enqueue(u)
+	return true
+}
+
+// refute 处理关于自己的消息：被怀疑或被宣布宕机时，提高 incarnation 并广播 alive 推翻它
+func (m *Memberlist) refute(u Member) bool {
+	self := m.members[m.cfg.Name]
+	if u.State == StateAlive || self.State == StateLeft || u.Incarnation < self.Incarnation {
+		return false
+	}
+	self.Incarnation = u.Incarnation + 1
+	m.logf("[Membership %s] refuting %s, incarnation %d", m.cfg.Name, u.State, self.Incarnation)
+	m.enqueue(self.Member)
+	return true
+}
+
+func (m *Memberlist) notify(name string, up bool) {
+	if m.cfg.Peers == nil {
+		return
+	}
+	if up {
+		m.cfg.Peers.AddPeers(name)
+	} else {
+		m.cfg.Peers.RemovePeers(name)
+	}
+}
+
+// enqueue 把状态变化放入待捎带的队列，同一个成员只保留最新的一条，调用时必须持有 m.mu
+func (m *Memberlist) enqueue(u Member) {
+	m.queue[u.Name] = &broadcast{update: u}
+}
+
+// takeBroadcasts 取出最多 maxPiggyback 条被捎带次数最少的状态变化，调用时必须持有 m.mu
+func (m *Memberlist) takeBroadcasts() []Member {
+	if len(m.queue) == 0 {
+		return nil
+	}
+	bs := make([]*broadcast, 0, len(m.queue))
+	for _, b := range m.queue {
+		bs = append(bs, b)
+	}
+	sort.Slice(bs, func(i, j int) bool {
+		if bs[i].transmits != bs[j].transmits {
+			return bs[i].transmits < bs[j].transmits
+		}
+		return bs[i].update.Name < bs[j].update.Name
+	})
+	limit := retransmitMult * int(math.Ceil(math.Log10(float64(len(m.members)+1))))
+	var out []Member
+	for _, b := range bs {
+		if len(out) == maxPiggyback {
+			break
+		}
+		out = append(out, b.update)
+		b.transmits++
+		if b.transmits >= limit {
+			delete(m.queue, b.update.Name)
+		}
+	}
+	return out
+}
+
+func (m *Memberlist) merge(updates []Member) {
+	if len(updates) == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range updates {
+		m.apply(u)
+	}
+}
+
+/*
+***********************网络消息*********************************
+ */
+
+type msgType int
+
+const (
+	msgPing    msgType = iota + 1 // 探测，接收方回复 ack
+	msgPingReq                    // 请接收方代为探测 Target，成功后由接收方回复 ack
+	msgAck                        // ping 或 ping-req 的回复
+	msgJoin                       // 新节点加入，接收方回复 sync
+	msgSync                       // 完整的成员列表
+	msgAlive                      // 只用于捎带状态变化，不需要回复
+)
+
+type message struct {
+	Type    msgType  `json:"type"`
+	Seq     uint64   `json:"seq,omitempty"`
+	Target  string   `json:"target,omitempty"`  // ping-req 要探测的地址
+	Updates []Member `json:"updates,omitempty"` // 捎带的状态变化
+}
+
+func (m *Memberlist) send(addr string, msg message) error {
+	if msg.Updates == nil && msg.Type != msgSync {
+		m.mu.Lock()
+		msg.Updates = m.takeBroadcasts()
+		m.mu.Unlock()
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if len(b) > maxPacketSize {
+		return fmt.Errorf("membership: message of %d bytes is too large", len(b))
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return err
+	}
+	_, err = m.conn.WriteToUDP(b, udpAddr)
+	return err
+}
+
+// expect 注册一个等待回复的序号，收到带有该序号的 ack 或 sync 时调用 fn
+func (m *Memberlist) expect(fn func(message)) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seq++
+	m.acks[m.seq] = fn
+	return m.seq
+}
+
+func (m *Memberlist) forget(seq uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.acks, seq)
+}
+
+func (m *Memberlist) readLoop() {
+	defer m.wg.Done()
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, from, err := m.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-m.done:
+				return
+			default:
+			}
+			m.logf("[Membership %s] read: %v", m.cfg.Name, err)
+			continue
+		}
+		var msg message
+		if err := json.Unmarshal(buf[:n], &msg); err != nil {
+			m.logf("[Membership %s] bad message from %s: %v", m.cfg.Name, from, err)
+			continue
+		}
+		m.handle(from.String(), msg)
+	}
+}
+
+func (m *Memberlist) handle(from string, msg message) {
+	m.merge(msg.Updates)
+	switch msg.Type {
+	case msgPing:
+		m.send(from, message{Type: msgAck, Seq: msg.Seq})
+	case msgPingReq:
+		// 代为探测，收到目标的 ack 后用请求方的序号回复请求方
+		var seq uint64
+		seq = m.expect(func(message) {
+			m.forget(seq)
+			m.send(from, message{Type: msgAck, Seq: msg.Seq})
+		})
+		m.send(msg.Target, message{Type: msgPing, Seq: seq})
+		// 目标一直没有回复时，清理掉等待的序号
+		time.AfterFunc(m.cfg.ProbeTimeout, func() { m.forget(seq) })
+	case msgJoin:
+		m.send(from, message{Type: msgSync, Seq: msg.Seq, Updates: m.snapshot()})
+	case msgAck, msgSync:
+		m.mu.Lock()
+		fn := m.acks[msg.Seq]
+		m.mu.Unlock()
+		if fn != nil {
+			fn(msg)
+		}
+	}
+}
+
+// snapshot 返回所有成员的状态，用于回复新加入的节点
+func (m *Memberlist) snapshot() []Member {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Member, 0, len(m.members))
+	for _, mem := range m.members {
+		out = append(out, mem.Member)
+	}
+	return out
+}
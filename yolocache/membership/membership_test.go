@@ -0,0 +1,170 @@
+package membership
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePeers 记录 Memberlist 通知的节点列表，代替 HTTPPool
+type fakePeers struct {
+	mu    sync.Mutex
+	peers map[string]bool
+}
+
+func (f *fakePeers) AddPeers(peers ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range peers {
+		f.peers[p] = true
+	}
+}
+
+func (f *fakePeers) RemovePeers(peers ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range peers {
+		delete(f.peers, p)
+	}
+}
+
+func (f *fakePeers) list() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []string
+	for p := range f.peers {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return fmt.Sprint(out)
+}
+
+type testNode struct {
+	*Memberlist
+	peers *fakePeers
+}
+
+// startNodes 在回环地址上启动n个节点，除第一个外都以第一个节点为种子加入集群
+func startNodes(t *testing.T, n int) []testNode {
+	var nodes []testNode
+	for i := 0; i < n; i++ {
+		peers := &fakePeers{peers: make(map[string]bool)}
+		m, err := New(Config{
+			Name:           fmt.Sprintf("http://node%d", i),
+			BindAddr:       "127.0.0.1:0",
+			Peers:          peers,
+			ProbeInterval:  20 * time.Millisecond,
+			ProbeTimeout:   10 * time.Millisecond,
+			SuspectTimeout: 100 * time.Millisecond,
+			Logf:           t.Logf,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { m.Close() })
+		if i > 0 {
+			if _, err := m.Join(nodes[0].Addr()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		nodes = append(nodes, testNode{m, peers})
+	}
+	return nodes
+}
+
+// eventually 在超时前反复检查 cond
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	nodes := startNodes(t, 3)
+	want := "[http://node0 http://node1 http://node2]"
+	for i, n := range nodes {
+		eventually(t, fmt.Sprintf("node%d to see all members", i), func() bool {
+			return n.peers.list() == want && len(n.Members()) == 3
+		})
+	}
+}
+
+func TestJoinNoSeed(t *testing.T) {
+	m, err := New(Config{Name: "http://lonely", BindAddr: "127.0.0.1:0", ProbeTimeout: 10 * time.Millisecond, Logf: t.Logf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	// 一个没有人监听的地址
+	dead, _ := New(Config{Name: "http://dead", BindAddr: "127.0.0.1:0", Logf: t.Logf})
+	addr := dead.Addr()
+	dead.Close()
+	if _, err := m.Join(addr); err == nil {
+		t.Fatalf("Join should fail when no seed responds")
+	}
+}
+
+func TestFailureDetection(t *testing.T) {
+	nodes := startNodes(t, 3)
+	for _, n := range nodes {
+		n := n
+		eventually(t, "convergence", func() bool { return len(n.Members()) == 3 })
+	}
+	// 不通知其他节点直接关闭，模拟宕机
+	nodes[2].Close()
+	want := "[http://node0 http://node1]"
+	for _, n := range nodes[:2] {
+		n := n
+		eventually(t, "the failed node to be removed", func() bool { return n.peers.list() == want })
+	}
+}
+
+func TestLeave(t *testing.T) {
+	nodes := startNodes(t, 3)
+	for _, n := range nodes {
+		n := n
+		eventually(t, "convergence", func() bool { return len(n.Members()) == 3 })
+	}
+	if err := nodes[1].Leave(); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []testNode{nodes[0], nodes[2]} {
+		n := n
+		eventually(t, "the leaving node to be removed", func() bool {
+			return n.peers.list() == "[http://node0 http://node2]"
+		})
+	}
+}
+
+// 节点被错误地怀疑时，会提高 incarnation 推翻这个怀疑，其他节点不会把它移除
+func TestRefute(t *testing.T) {
+	nodes := startNodes(t, 2)
+	for _, n := range nodes {
+		n := n
+		eventually(t, "convergence", func() bool { return len(n.Members()) == 2 })
+	}
+	before := nodes[1].self().Incarnation
+	nodes[0].mu.Lock()
+	nodes[0].apply(Member{Name: "http://node1", Addr: nodes[1].Addr(), State: StateSuspect, Incarnation: before})
+	nodes[0].mu.Unlock()
+
+	eventually(t, "node1 to refute", func() bool { return nodes[1].self().Incarnation > before })
+	eventually(t, "node0 to see node1 alive again", func() bool {
+		for _, m := range nodes[0].Members() {
+			if m.Name == "http://node1" {
+				return m.State == StateAlive
+			}
+		}
+		return false
+	})
+	if got := nodes[0].peers.list(); got != "[http://node0 http://node1]" {
+		t.Fatalf("peers = %s", got)
+	}
+}
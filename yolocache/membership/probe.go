@@ -0,0 +1,122 @@
+package membership
+
+import (
+	"math/rand"
+	"time"
+)
+
+/*
+***********************故障检测*********************************
+每个 ProbeInterval 按随机顺序探测下一个成员，一轮探测完所有成员后重新打乱顺序，
+这样每个成员在有限的时间内一定会被探测到。
+*/
+
+func (m *Memberlist) probeLoop() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(m.cfg.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.probe()
+			m.expireSuspects()
+		}
+	}
+}
+
+// probe 探测一个成员：先直接 ping，超时后请其他成员代为 ping，仍然失败就把它标记为 suspect
+func (m *Memberlist) probe() {
+	target, ok := m.nextTarget()
+	if !ok {
+		return
+	}
+	acked := make(chan struct{}, 1)
+	seq := m.expect(func(message) {
+		select {
+		case acked <- struct{}{}:
+		default:
+		}
+	})
+	defer m.forget(seq)
+
+	wait := func() bool {
+		select {
+		case <-acked:
+			return true
+		case <-time.After(m.cfg.ProbeTimeout):
+			return false
+		case <-m.done:
+			return true
+		}
+	}
+	m.send(target.Addr, message{Type: msgPing, Seq: seq})
+	if wait() {
+		return
+	}
+	for _, helper := range m.randomMembers(m.cfg.IndirectChecks, target.Name) {
+		m.send(helper.Addr, message{Type: msgPingReq, Seq: seq, Target: target.Addr})
+	}
+	if wait() {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.apply(Member{Name: target.Name, Addr: target.Addr, State: StateSuspect, Incarnation: target.Incarnation})
+}
+
+// nextTarget 返回本轮要探测的下一个成员
+func (m *Memberlist) nextTarget() (Member, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for {
+		if len(m.probes) == 0 {
+			for name, mem := range m.members {
+				if name != m.cfg.Name && !mem.State.down() {
+					m.probes = append(m.probes, name)
+				}
+			}
+			if len(m.probes) == 0 {
+				return Member{}, false
+			}
+			rand.Shuffle(len(m.probes), func(i, j int) { m.probes[i], m.probes[j] = m.probes[j], m.probes[i] })
+		}
+		name := m.probes[0]
+		m.probes = m.probes[1:]
+		// 打乱之后成员可能已经宕机了
+		if mem := m.members[name]; !mem.State.down() {
+			return mem.Member, true
+		}
+	}
+}
+
+// randomMembers 随机返回最多k个 alive 的成员，不包括自己和 exclude
+func (m *Memberlist) randomMembers(k int, exclude string) []Member {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []Member
+	for name, mem := range m.members {
+		if name != m.cfg.Name && name != exclude && mem.State == StateAlive {
+			out = append(out, mem.Member)
+		}
+	}
+	rand.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	if len(out) > k {
+		out = out[:k]
+	}
+	return out
+}
+
+// expireSuspects 把 suspect 超过 SuspectTimeout 还没有被推翻的成员标记为 dead
+func (m *Memberlist) expireSuspects() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for _, mem := range m.members {
+		if mem.State == StateSuspect && now.Sub(mem.suspectAt) > m.cfg.SuspectTimeout {
+			m.apply(Member{Name: mem.Name, Addr: mem.Addr, State: StateDead, Incarnation: mem.Incarnation})
+		}
+	}
+}
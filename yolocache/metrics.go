@@ -0,0 +1,206 @@
+package yolocache
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+***********************Prometheus文本格式的监控指标*********************************
+不依赖 Prometheus 的客户端库，直接按照文本格式(text exposition format)输出，
+包括每个Group的计数、缓存的大小和淘汰数、singleflight中正在执行的请求数，以及每个远程节点的RPC耗时分布。
+*/
+
+// 远程节点RPC耗时直方图的桶上界，单位为秒
+var defaultLatencyBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// histogram 是一个简单的累积直方图，对应 Prometheus 的 histogram 类型
+type histogram struct {
+	mu      sync.Mutex
+	buckets []float64 // 每个桶的上界，升序
+	counts  []uint64  // counts[i] 是落在 (buckets[i-1], buckets[i]] 中的观测数，最后一个元素对应 +Inf
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)+1),
+	}
+}
+
+// observe 记录一次耗时
+func (h *histogram) observe(d time.Duration) {
+	v := d.Seconds()
+	i := sort.SearchFloat64s(h.buckets, v) // 第一个 >= v 的桶
+	h.mu.Lock()
+	h.counts[i]++
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+// write 按文本格式输出直方图，labels 是不含大括号的标签串，如 peer="http://localhost:8001"
+func (h *histogram) write(w io.Writer, name, labels string) {
+	h.mu.Lock()
+	counts := append([]uint64(nil), h.counts...)
+	sum, count := h.sum, h.count
+	h.mu.Unlock()
+
+	var cumulative uint64
+	for i, le := range h.buckets {
+		cumulative += counts[i]
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"%g\"} %d\n", name, labels, le, cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, count)
+	fmt.Fprintf(w, "%s_sum{%s} %g\n", name, labels, sum)
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, count)
+}
+
+var (
+	peerLatencyMu sync.Mutex
+	peerLatency   = make(map[string]*peerLatencyEntry) // 远程节点地址 -> 该节点Get RPC的耗时直方图
+)
+
+// peerLatencyEntry 记录直方图被多少个节点客户端使用，节点离开所有 Pool 后直方图被删除，
+// 否则节点频繁变化（如按 Pod IP 部署）时 /metrics 中会堆积大量已经不存在的节点
+type peerLatencyEntry struct {
+	h    *histogram
+	refs int
+}
+
+// peerLatencyHistogram 返回远程节点对应的耗时直方图，同一个节点在多个 Pool 中共用一个直方图。
+// 节点被移除时需要调用 releasePeerLatency
+func peerLatencyHistogram(peer string) *histogram {
+	peerLatencyMu.Lock()
+	defer peerLatencyMu.Unlock()
+	e, ok := peerLatency[peer]
+	if !ok {
+		e = &peerLatencyEntry{h: newHistogram(defaultLatencyBuckets)}
+		peerLatency[peer] = e
+	}
+	e.refs++
+	return e.h
+}
+
+// releasePeerLatency 与 peerLatencyHistogram 成对调用，最后一个使用者释放后删除这个节点的直方图
+func releasePeerLatency(peer string) {
+	peerLatencyMu.Lock()
+	defer peerLatencyMu.Unlock()
+	if e, ok := peerLatency[peer]; ok {
+		if e.refs--; e.refs <= 0 {
+			delete(peerLatency, peer)
+		}
+	}
+}
+
+// MetricsHandler 返回一个输出 Prometheus 文本格式监控指标的 http.Handler，
+// 一般挂载在 /metrics 路径上，与 HTTPPool 一起注册到同一个 ServeMux 中
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(w)
+	})
+}
+
+// groupCounter 描述一个按Group输出的计数指标
+type groupCounter struct {
+	name, help string
+	value      func(Stats) int64
+}
+
+var groupCounters = []groupCounter{
+	{"yolocache_gets_total", "Get requests, including requests from peers.", func(s Stats) int64 { return s.Gets }},
+	{"yolocache_cache_hits_total", "Get requests served from the main or hot cache.", func(s Stats) int64 { return s.CacheHits }},
+	{"yolocache_negative_hits_total", "Get requests answered from the negative cache.", func(s Stats) int64 { return s.NegativeHits }},
+	{"yolocache_loads_total", "Get requests that missed the cache.", func(s Stats) int64 { return s.Loads }},
+	{"yolocache_loads_deduped_total", "Loads that shared the result of a concurrent load.", func(s Stats) int64 { return s.LoadsDeduped }},
+	{"yolocache_peer_loads_total", "Values fetched from peers.", func(s Stats) int64 { return s.PeerLoads }},
+	{"yolocache_peer_errors_total", "Failed peer fetches.", func(s Stats) int64 { return s.PeerErrors }},
+	{"yolocache_local_loads_total", "Values loaded by the Getter.", func(s Stats) int64 { return s.LocalLoads }},
+	{"yolocache_local_load_errors_total", "Getter calls that returned an error.", func(s Stats) int64 { return s.LocalLoadErrs }},
+	{"yolocache_server_requests_total", "Get requests received from peers.", func(s Stats) int64 { return s.ServerRequests }},
+	{"yolocache_ring_mismatches_total", "Peer requests sent with a ring digest different from ours.", func(s Stats) int64 { return s.RingMismatches }},
+	{"yolocache_hedges_total", "Peer fetches that were hedged after the hedge delay.", func(s Stats) int64 { return s.Hedges }},
+	{"yolocache_hedge_wins_total", "Hedged fetches that answered before the first peer.", func(s Stats) int64 { return s.HedgeWins }},
+}
+
+// cacheMetric 描述一个按Group和缓存类型输出的指标
+type cacheMetric struct {
+	name, help, typ string
+	value           func(CacheStats) int64
+}
+
+var cacheMetrics = []cacheMetric{
+	{"yolocache_cache_bytes", "Bytes currently held by the cache.", "gauge", func(s CacheStats) int64 { return s.Bytes }},
+	{"yolocache_cache_items", "Entries currently held by the cache.", "gauge", func(s CacheStats) int64 { return s.Items }},
+	{"yolocache_cache_evictions_total", "Entries evicted because the cache was full.", "counter", func(s CacheStats) int64 { return s.Evictions }},
+}
+
+// writeMetrics 输出所有指标，Group和节点都按名称排序，保证每次输出的顺序稳定
+func writeMetrics(w io.Writer) {
+	gs := allGroups()
+	sort.Slice(gs, func(i, j int) bool { return gs[i].name < gs[j].name })
+
+	stats := make([]Stats, len(gs))
+	for i, g := range gs {
+		stats[i] = g.Stats()
+	}
+	for _, c := range groupCounters {
+		writeHeader(w, c.name, c.help, "counter")
+		for i, g := range gs {
+			fmt.Fprintf(w, "%s{group=\"%s\"} %d\n", c.name, escapeLabel(g.name), c.value(stats[i]))
+		}
+	}
+
+	caches := []struct {
+		name string
+		typ  CacheType
+	}{{"main", MainCache}, {"hot", HotCache}, {"negative", NegativeCache}}
+	for _, m := range cacheMetrics {
+		writeHeader(w, m.name, m.help, m.typ)
+		for _, g := range gs {
+			for _, c := range caches {
+				fmt.Fprintf(w, "%s{group=\"%s\",cache=\"%s\"} %d\n", m.name, escapeLabel(g.name), c.name, m.value(g.CacheStats(c.typ)))
+			}
+		}
+	}
+
+	// 本地调用者的加载和其他节点转发过来的加载分别由 loader 和 localLoader 去重，两者都要算上
+	writeHeader(w, "yolocache_singleflight_inflight", "Loads currently in flight.", "gauge")
+	for _, g := range gs {
+		fmt.Fprintf(w, "yolocache_singleflight_inflight{group=\"%s\"} %d\n", escapeLabel(g.name), g.loader.InFlight()+g.localLoader.InFlight())
+	}
+
+	peerLatencyMu.Lock()
+	peers := make([]string, 0, len(peerLatency))
+	latency := make(map[string]*histogram, len(peerLatency))
+	for peer, e := range peerLatency {
+		peers = append(peers, peer)
+		latency[peer] = e.h
+	}
+	peerLatencyMu.Unlock()
+	sort.Strings(peers)
+	writeHeader(w, "yolocache_peer_rpc_duration_seconds", "Latency of Get RPCs to peers.", "histogram")
+	for _, peer := range peers {
+		latency[peer].write(w, "yolocache_peer_rpc_duration_seconds", fmt.Sprintf("peer=\"%s\"", escapeLabel(peer)))
+	}
+}
+
+func writeHeader(w io.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+// labelEscaper 转义标签值中的反斜杠、双引号和换行符
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,192 @@
+package yolocache
+
+import (
+	pb "YoloCache/yolocache/yolocachepb"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+)
+
+/*
+***********************批量获取*********************************
+一个页面往往需要几十上百个key，逐个调用 Get 就是逐个的网络往返。GetMulti 的流程：
+  - 先查本地缓存，命中的直接返回
+  - 未命中的key按 PickPeer 选出的节点分组，每个节点只发送一次批量请求（节点实现了 BatchGetter 时）
+  - 与 Get 一样，每个key都经过 g.loader 去重：已经有请求在进行中的key不放进批量请求，而是等待那次请求的结果
+  - 由本节点负责的key，以及批量请求失败的key，并发地走与 Get 相同的加载流程
+*/
+
+// Result 是 GetMulti 中一个key的结果
+type Result struct {
+	Value ByteView
+	Err   error
+}
+
+// GetMulti 批量获取多个key，返回的结果与 keys 一一对应
+func (g *Group) GetMulti(keys []string) []Result {
+	return g.GetMultiContext(context.Background(), keys)
+}
+
+// GetMultiContext 与 GetMulti 相同，ctx 的取消和超时会传递到批量请求和每个key的加载上
+func (g *Group) GetMultiContext(ctx context.Context, keys []string) []Result {
+	results := make([]Result, len(keys))
+	// 未命中缓存的key -> 它在 keys 中出现的位置，重复的key只加载一次
+	misses := make(map[string][]int)
+	var order []string
+	for i, key := range keys {
+		if key == "" {
+			results[i].Err = fmt.Errorf("key is required")
+			continue
+		}
+		g.stats.gets.Add(1)
+		if v, err, ok := g.lookupCache(key); ok {
+			results[i] = Result{Value: v, Err: err}
+			continue
+		}
+		if _, ok := misses[key]; !ok {
+			order = append(order, key)
+		}
+		misses[key] = append(misses[key], i)
+	}
+	set := func(key string, v ByteView, err error) {
+		for _, i := range misses[key] {
+			results[i] = Result{Value: v, Err: err}
+		}
+	}
+
+	// 按节点分组，本节点负责的key以及不支持批量请求的节点上的key逐个加载
+	byPeer := make(map[BatchGetter][]string)
+	var single []string
+	for _, key := range order {
+		if peers := g.pickPeers(key); len(peers) > 0 {
+			if bg, ok := peers[0].(BatchGetter); ok {
+				byPeer[bg] = append(byPeer[bg], key)
+				continue
+			}
+		}
+		single = append(single, key)
+	}
+
+	var wg sync.WaitGroup
+	for peer, batch := range byPeer {
+		wg.Add(1)
+		go func(peer BatchGetter, batch []string) {
+			defer wg.Done()
+			g.loadBatch(ctx, peer, batch, set)
+		}(peer, batch)
+	}
+	for _, key := range single {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			v, err := g.load(ctx, key)
+			set(key, v, err)
+		}(key)
+	}
+	wg.Wait()
+	return results
+}
+
+// loadBatch 与 load 一样通过 g.loader 加载一个节点上的keys：已经有请求在进行中的key直接等待那次请求的结果，
+// 其余的key合并成一次批量请求，批量请求没有给出确定结果的key（比如所有者过载）再按 load 的流程逐个加载
+func (g *Group) loadBatch(ctx context.Context, peer BatchGetter, keys []string, set func(string, ByteView, error)) {
+	type result struct {
+		value ByteView
+		err   error
+	}
+	fetched := make(map[string]result, len(keys)) // 批量请求得到的确定结果，done 关闭后只读
+	done := make(chan struct{})
+	waits := make([]func() (interface{}, error), len(keys))
+	var batch []string
+	for i, key := range keys {
+		key := key
+		g.stats.loads.Add(1)
+		wait, shared := g.loader.DoAsync(ctx, key, func(ctx context.Context) (interface{}, error) {
+			select {
+			case <-done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if r, ok := fetched[key]; ok {
+				return r.value, r.err
+			}
+			return g.fetch(ctx, key)
+		})
+		if shared {
+			g.stats.loadsDeduped.Add(1)
+		} else {
+			batch = append(batch, key)
+		}
+		waits[i] = wait
+	}
+	if len(batch) > 0 {
+		g.getBatchFromPeer(ctx, peer, batch, func(key string, v ByteView, err error) {
+			fetched[key] = result{v, err}
+		})
+	}
+	close(done)
+	for i, key := range keys {
+		v, err := waits[i]()
+		view, _ := v.(ByteView)
+		set(key, view, err)
+	}
+}
+
+// getBatchFromPeer 向一个节点批量获取keys，确定的结果通过 set 写入，没有写入的key由调用方逐个重新加载
+func (g *Group) getBatchFromPeer(ctx context.Context, peer BatchGetter, keys []string, set func(string, ByteView, error)) {
+	req := &pb.BatchRequest{
+		Group: g.name,
+		Keys:  keys,
+		Hops:  1, // 与 Get 相同，接收方只在本地加载，不再转发
+	}
+	res := &pb.BatchResponse{}
+	err := peer.GetBatch(ctx, req, res)
+	if err == nil && len(res.GetResponses()) != len(keys) {
+		err = fmt.Errorf("batch response has %d values for %d keys", len(res.GetResponses()), len(keys))
+	}
+	if err != nil {
+		// 调用方已经放弃时不算节点的错误
+		if ctx.Err() == nil {
+			g.stats.peerErrors.Add(1)
+			log.Println("[YoloCache] Failed to get batch from peer", err)
+		}
+		return
+	}
+	for i, key := range keys {
+		r := res.GetResponses()[i]
+		err := errorFromResponse(r)
+		switch {
+		case err == nil:
+			g.stats.peerLoads.Add(1)
+			value := ByteView{b: r.GetValue()}
+			g.maybePopulateHotCache(key, value)
+			set(key, value, nil)
+		case isAuthoritative(err):
+			g.stats.peerLoads.Add(1)
+			g.populateNegativeCache(key, err)
+			set(key, ByteView{}, err)
+		}
+		// 其他错误（如所有者过载）不写入结果，这个key按 load 的流程重新加载
+	}
+}
+
+// getBatchForPeer 处理其他节点发来的批量请求，并发地在本地加载每个key，结果与 keys 一一对应
+func (g *Group) getBatchForPeer(ctx context.Context, keys []string) []*pb.Response {
+	out := make([]*pb.Response, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, key string) {
+			defer wg.Done()
+			view, err := g.getForPeer(ctx, key)
+			if err != nil {
+				out[i] = responseFromError(err)
+				return
+			}
+			out[i] = &pb.Response{Value: view.b}
+		}(i, key)
+	}
+	wg.Wait()
+	return out
+}
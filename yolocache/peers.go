@@ -1,6 +1,9 @@
 package yolocache
 
-import pb "YoloCache/yolocache/yolocachepb"
+import (
+	pb "YoloCache/yolocache/yolocachepb"
+	"context"
+)
 
 /*
 *******************************注册节点， 借助一致性哈希算法选择节点*********************************
@@ -32,6 +35,19 @@ type PeerPicker interface {
 	PickPeer(key string) (peer PeerGetter, ok bool)
 }
 
+// ReplicaPicker 是 PeerPicker 的可选扩展，每个key有多个副本节点。
+// PickPeers 按顺序返回要尝试的副本，第一个是 PickPeer 会选中的节点；副本中包含本节点时，列表在本节点之前截止，
+// 因为本节点本身就是副本，轮到它时直接在本地加载即可。第一个副本就是本节点时返回空
+type ReplicaPicker interface {
+	PickPeers(key string) []PeerGetter
+}
+
+// OwnerPicker 是 PeerPicker 的可选扩展。PickPeer 可能出于负载等原因选中不是所有者的节点，
+// 这对读请求没有问题，但写请求必须发给真正的所有者，实现了 PickOwner 的 PeerPicker 会被写请求使用
+type OwnerPicker interface {
+	PickOwner(key string) (peer PeerGetter, ok bool)
+}
+
 // PeerGetter 对应于上述流程中的HTTP客户端，使用Get方法从对应group查找缓存值
 // 该接口对应着，http客户端请求去找值
 //type PeerGetter interface {
@@ -40,7 +56,17 @@ type PeerPicker interface {
 //}
 
 type PeerGetter interface {
-	// Get 用于从对应group查找缓存值
+	// Get 用于从对应group查找缓存值, ctx 的取消和超时会一直传递到与远程节点的通信上
 	//Get(in *pb.Request, out *pb.Response) ([]byte, error)
-	Get(in *pb.Request, out *pb.Response) error
+	Get(ctx context.Context, in *pb.Request, out *pb.Response) error
+	// Set 将值写入到远程节点（key的所有者）的缓存中
+	Set(ctx context.Context, in *pb.SetRequest, out *pb.Response) error
+	// Remove 删除远程节点（key的所有者）中的缓存值
+	Remove(ctx context.Context, in *pb.Request, out *pb.Response) error
+}
+
+// BatchGetter 是 PeerGetter 的可选扩展，一次请求获取同一个Group中的多个key。
+// out.Responses 与 in.Keys 一一对应；返回错误表示整个请求失败（如网络错误），这时调用方会逐个key回退
+type BatchGetter interface {
+	GetBatch(ctx context.Context, in *pb.BatchRequest, out *pb.BatchResponse) error
 }
@@ -1,6 +1,12 @@
 package singleflight
 
-import "sync"
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
 
 // 为了避免缓存击穿，我们需要在高并发场景下，对相同的key，只让一个请求去查询数据，其他请求等待这个请求的结果即可
 
@@ -11,10 +17,28 @@ import "sync"
 // 关于如何抽象出来call？
 // TODO  表示这个请求的状态？ 主要通过wg的状态来表示 错 key就已经可以表示当前请求的状态了，
 // TODO 现在的想法是：  call主要是为了实现重入锁wg，以及fn的执行状态和结果，因为防止重入，本质上是要防止fn的重复执行
+// 最初这里用的是 sync.WaitGroup，但 wg.Wait() 无法和 ctx.Done() 一起 select，
+// 等待者在超时或取消时就没法提前返回，所以换成了一个在 fn 结束时被 close 的 channel。
 type call struct {
-	wg  sync.WaitGroup // 用于实现重入锁  // 为什么要用sync.WaitGroup呢？ 并发协程之间不需要消息传递，非常适合 sync.WaitGroup。
-	val interface{}    // 函数执行的结果
-	err error          // 函数执行的错误
+	done chan struct{} // fn 执行结束后关闭，等待者通过它得知结果已就绪
+	val  interface{}   // 函数执行的结果
+	err  error         // 函数执行的错误
+
+	panicErr *panicError // fn 发生了 panic，每个等待者都会重新 panic
+
+	waiters int          // 还在等待结果的调用者数，包括发起者，由 Group.mu 保护
+	ctx     *callContext // 传给 fn 的 ctx，所有调用者都放弃时被取消
+}
+
+// panicError 记录 fn 中的 panic 和当时的调用栈。fn 在单独的协程中执行，
+// 不能让 panic 直接把整个进程带走，而是交给每个等待者重新 panic，就像 fn 是在它们自己的协程中执行的一样
+type panicError struct {
+	value interface{}
+	stack []byte
+}
+
+func (p *panicError) Error() string {
+	return fmt.Sprintf("%v\n\n%s", p.value, p.stack)
 }
 
 // Group 是 singleflight 的主数据结构，管理不同 key 的请求(call)。
@@ -32,6 +56,27 @@ type Group struct {
 // 这里为什么要传入一个fn呢？ TODO
 // 对于参数的类型，因为不确定，所以使用了interface{}，对于返回值，因为不确定，所以使用了interface{}和error
 func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	v, err, _ := g.DoContext(context.Background(), key, func(context.Context) (interface{}, error) {
+		return fn()
+	})
+	return v, err
+}
+
+// DoContext 与 Do 相同，但每个调用者只受自己的 ctx 控制：
+// fn 在单独的协程中执行，拿到的 ctx 保留了发起者 ctx 中的值，但不继承它的取消，
+// 否则发起者超时或取消时，等待同一个 key 的其他调用者也会一起失败；它的截止时间是所有调用者中最晚的一个。
+// 任何调用者（包括发起者）的 ctx 先结束时直接返回 ctx.Err()，fn 继续为其他调用者执行；
+// 所有调用者都放弃后，传给 fn 的 ctx 才会被取消。fn 发生 panic 时，每个等待者都会重新 panic。
+// 返回的 shared 表示本次调用是否复用了其他调用者的结果（即没有自己执行 fn）。
+func (g *Group) DoContext(ctx context.Context, key string, fn func(context.Context) (interface{}, error)) (v interface{}, err error, shared bool) {
+	wait, shared := g.DoAsync(ctx, key, fn)
+	v, err = wait()
+	return v, err, shared
+}
+
+// DoAsync 与 DoContext 相同，但不等待结果就返回，调用方可以先知道这个key是否已经有请求在进行中（shared），
+// 比如批量获取时只把自己发起的key放进批量请求。wait 阻塞到结果就绪或 ctx 结束，必须调用且只能调用一次
+func (g *Group) DoAsync(ctx context.Context, key string, fn func(context.Context) (interface{}, error)) (wait func() (interface{}, error), shared bool) {
 	/* 对于每一个请求，都有两种情况:
 	1. 这个key的请求从来没有被发起过
 	2. 已经有相同key的请求正在进行中
@@ -45,29 +90,157 @@ func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, err
 	}
 	// 肯定首先是要尝试从map中获取这个key的请求状态
 	// 读map，需要加锁
-	if c, ok := g.m[key]; ok {
-		// 读取到后解锁  TODO 这把锁的作用范围
-		g.mu.Unlock()
-		// 如果这个key的请求正在进行中，则等待这个请求结束，返回结果或者错误
-		c.wg.Wait()
-		// 如果这个key的请求已经结束了，则删除这个key的请求状态，返回结果或者错误
-		return c.val, c.err // Wait结束后，这里的c已经被前面进行的那次请求修改成结果了，所以这里不需要再调用fn
+	c, shared := g.m[key]
+	if !shared {
+		// 如果map中没有这个key的请求，则发起这个请求
+		// 实例化一个c, 将用来承载fn的返回值
+		c = &call{done: make(chan struct{}), ctx: newCallContext(ctx)}
+		g.m[key] = c // 将这个key的请求状态添加到map中，表明key已经有对应的请求在处理
+		go g.run(key, c, fn)
+	} else {
+		c.ctx.join(ctx) // 新的等待者可能愿意等得更久
 	}
-	// 如果map中没有这个key的请求，则发起这个请求，返回结果或者错误
-	// 实例化一个c, 将用来承载fn的返回值
-	c := new(call)
-	c.wg.Add(1) // 发起请求前，加锁,表示有一个请求正在进行中
-	// 因为进行到这里，说明没走上面判断key在进行中，所以上面读map的锁还没有解锁
-	g.m[key] = c // 将这个key的请求状态添加到map中，表明key已经有对应的请求在处理
+	c.waiters++
 	g.mu.Unlock()
-	c.val, c.err = fn() // 发起请求, 执行fn函数
-	// TODO v1 先写的是不考虑并发的版本，所以v1里没有使用group的mu, 思考在并发情况下，应该在哪里加锁？
-	c.wg.Done() // 请求结束，解锁， 这时在上面的Wait被释放
-	// 要进行删除操作，所以要加锁
+	return func() (interface{}, error) {
+		// 等待这个请求结束，或者等待自己的ctx结束
+		select {
+		case <-c.done:
+			if c.panicErr != nil {
+				panic(c.panicErr)
+			}
+			return c.val, c.err // done关闭后，这里的c已经被执行fn的协程修改成结果了
+		case <-ctx.Done():
+			g.leave(key, c)
+			return nil, ctx.Err()
+		}
+	}, shared
+}
+
+// run 执行fn并唤醒所有等待者
+func (g *Group) run(key string, c *call, fn func(context.Context) (interface{}, error)) {
+	defer c.ctx.cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			c.panicErr = &panicError{value: r, stack: debug.Stack()}
+		}
+		close(c.done) // 请求结束，唤醒所有等待者
+		// 要进行删除操作，所以要加锁
+		g.mu.Lock()
+		if g.m[key] == c { // 所有调用者都放弃时，key 已经被 leave 删除，可能又有新的请求
+			delete(g.m, key)
+		}
+		g.mu.Unlock()
+	}()
+	c.val, c.err = fn(c.ctx) // 发起请求, 执行fn函数
+}
+
+// leave 在调用者放弃等待时调用，最后一个调用者离开时取消fn，
+// 并从map中删除这个key，之后的调用者会发起新的请求，而不是拿到一个被取消的结果
+func (g *Group) leave(key string, c *call) {
 	g.mu.Lock()
-	delete(g.m, key) // 跟新g.m,删除key
-	g.mu.Unlock()
-	return c.val, c.err // 返回结果
+	defer g.mu.Unlock()
+	c.waiters--
+	if c.waiters > 0 {
+		return
+	}
+	c.ctx.cancel()
+	if g.m[key] == c {
+		delete(g.m, key)
+	}
+}
+
+// callContext 是传给 fn 的 ctx：保留发起者 ctx 中的值，但不继承它的取消（类似 Go 1.21 的 context.WithoutCancel），
+// 截止时间取所有等待者中最晚的一个，有等待者没有截止时间时 fn 也没有截止时间，所有等待者都放弃时被取消。
+// 与标准库的 ctx 不同，新的等待者加入时 Deadline 可能推后，已经从它派生出的 ctx 仍然使用原来的截止时间
+type callContext struct {
+	parent context.Context
+	done   chan struct{}
+
+	mu       sync.Mutex
+	deadline time.Time   // 零值表示没有截止时间
+	timer    *time.Timer // 截止时间到达时取消
+	err      error
+}
+
+func newCallContext(parent context.Context) *callContext {
+	c := &callContext{parent: parent, done: make(chan struct{})}
+	c.mu.Lock() // 截止时间已经过去时 timer 可能立刻触发
+	defer c.mu.Unlock()
+	if d, ok := parent.Deadline(); ok {
+		c.deadline = d
+		c.timer = time.AfterFunc(time.Until(d), c.expire)
+	}
+	return c
+}
+
+// join 在新的等待者加入时调用，按它的截止时间推后（或者去掉）fn 的截止时间
+func (c *callContext) join(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.deadline.IsZero() || c.err != nil {
+		return
+	}
+	d, ok := ctx.Deadline()
+	if ok && !d.After(c.deadline) {
+		return
+	}
+	c.timer.Stop()
+	if !ok {
+		c.deadline = time.Time{}
+		return
+	}
+	c.deadline = d
+	c.timer = time.AfterFunc(time.Until(d), c.expire)
+}
+
+// expire 在截止时间到达时调用，截止时间已经被推后时什么也不做
+func (c *callContext) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.deadline.IsZero() && !time.Now().Before(c.deadline) {
+		c.cancelLocked(context.DeadlineExceeded)
+	}
+}
+
+func (c *callContext) cancel() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cancelLocked(context.Canceled)
+}
+
+func (c *callContext) cancelLocked(err error) {
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	close(c.done)
+}
+
+func (c *callContext) Deadline() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deadline, !c.deadline.IsZero()
+}
+
+func (c *callContext) Done() <-chan struct{} { return c.done }
+
+func (c *callContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *callContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// InFlight 返回当前正在执行（尚未结束）的请求数
+func (g *Group) InFlight() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.m)
 }
 
 // Do方法接收一个key和一个函数fn，如果这个key的请求正在进行中，则等待这个请求结束，返回结果或者错误
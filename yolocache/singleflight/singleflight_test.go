@@ -1,7 +1,10 @@
 package singleflight
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 )
 
 func TestDo(t *testing.T) {
@@ -14,3 +17,289 @@ func TestDo(t *testing.T) {
 		t.Errorf("Do v = %v, error = %v", v, err)
 	}
 }
+
+func TestDoContextWaiterCancel(t *testing.T) {
+	var g Group
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go g.DoContext(context.Background(), "key", func(context.Context) (interface{}, error) {
+		close(started)
+		<-release
+		return "bar", nil
+	})
+	<-started
+
+	// 相同 key 的等待者在自己的 ctx 超时后应直接返回，而不是一直阻塞
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	v, err, shared := g.DoContext(ctx, "key", func(context.Context) (interface{}, error) {
+		t.Fatal("fn should not be called for a duplicate key")
+		return nil, nil
+	})
+	if v != nil || !errors.Is(err, context.DeadlineExceeded) || !shared {
+		t.Errorf("DoContext v = %v, error = %v", v, err)
+	}
+	close(release)
+}
+
+func TestInFlight(t *testing.T) {
+	var g Group
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		g.Do("key", func() (interface{}, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+		close(done)
+	}()
+	<-started
+	if n := g.InFlight(); n != 1 {
+		t.Errorf("InFlight = %d during call, want 1", n)
+	}
+	close(release)
+	<-done
+	if n := g.InFlight(); n != 0 {
+		t.Errorf("InFlight = %d after call, want 0", n)
+	}
+}
+
+// 发起者超时不影响等待同一个 key 的其他调用者
+func TestDoContextLeaderDeadline(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	fnErr := make(chan error, 1)
+	short, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err, _ := g.DoContext(short, "key", func(ctx context.Context) (interface{}, error) {
+			select {
+			case <-release:
+				return "bar", nil
+			case <-ctx.Done():
+				fnErr <- ctx.Err()
+				return nil, ctx.Err()
+			}
+		})
+		leaderErr <- err
+	}()
+	for g.InFlight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	patient := make(chan interface{}, 1)
+	go func() {
+		v, err, shared := g.DoContext(context.Background(), "key", func(context.Context) (interface{}, error) {
+			t.Error("fn should not be called for a duplicate key")
+			return nil, nil
+		})
+		if err != nil || !shared {
+			t.Errorf("patient caller: v = %v, err = %v, shared = %v", v, err, shared)
+		}
+		patient <- v
+	}()
+
+	if err := <-leaderErr; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("leader err = %v, want its own deadline", err)
+	}
+	close(release)
+	if v := <-patient; v != "bar" {
+		t.Fatalf("patient caller got %v", v)
+	}
+	select {
+	case err := <-fnErr:
+		t.Fatalf("fn was cancelled (%v) while a caller was still waiting", err)
+	default:
+	}
+}
+
+// 所有调用者都放弃后 fn 被取消，之后的调用者重新发起请求
+func TestDoContextAllWaitersGone(t *testing.T) {
+	var g Group
+	cancelled := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err, _ := g.DoContext(ctx, "key", func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("fn was not cancelled after every caller gave up")
+	}
+	v, err, shared := g.DoContext(context.Background(), "key", func(context.Context) (interface{}, error) {
+		return "fresh", nil
+	})
+	if v != "fresh" || err != nil || shared {
+		t.Fatalf("DoContext after abandonment = %v, %v, shared = %v", v, err, shared)
+	}
+}
+
+// DoAsync 立即告诉调用方这个key是否已经有请求在进行中
+func TestDoAsync(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	wait1, shared := g.DoAsync(context.Background(), "key", func(context.Context) (interface{}, error) {
+		<-release
+		return "bar", nil
+	})
+	if shared {
+		t.Fatal("first caller should start fn")
+	}
+	wait2, shared := g.DoAsync(context.Background(), "key", func(context.Context) (interface{}, error) {
+		t.Error("fn should not be called for a duplicate key")
+		return nil, nil
+	})
+	if !shared {
+		t.Fatal("second caller should share the call in flight")
+	}
+	close(release)
+	for _, wait := range []func() (interface{}, error){wait1, wait2} {
+		if v, err := wait(); v != "bar" || err != nil {
+			t.Fatalf("wait() = %v, %v", v, err)
+		}
+	}
+}
+
+// fn 中的 panic 不会让进程崩溃，而是在每个等待者中重新 panic，之后这个key可以重新加载
+func TestDoContextPanic(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	recovered := make(chan interface{}, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			defer func() { recovered <- recover() }()
+			g.DoContext(context.Background(), "key", func(context.Context) (interface{}, error) {
+				<-release
+				panic("boom")
+			})
+		}()
+	}
+	for g.InFlight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond) // 等第二个调用者加入
+	close(release)
+	for i := 0; i < 2; i++ {
+		select {
+		case r := <-recovered:
+			p, ok := r.(*panicError)
+			if !ok || p.value != "boom" {
+				t.Fatalf("waiter recovered %v, want the panic from fn", r)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("waiter did not return after fn panicked")
+		}
+	}
+	v, err, _ := g.DoContext(context.Background(), "key", func(context.Context) (interface{}, error) {
+		return "bar", nil
+	})
+	if v != "bar" || err != nil {
+		t.Fatalf("DoContext after panic = %v, %v", v, err)
+	}
+}
+
+// fn 的截止时间是所有等待者中最晚的一个，有等待者没有截止时间时 fn 也没有截止时间
+func TestDoContextDeadline(t *testing.T) {
+	var g Group
+	leaderCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	laterCtx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	leaderDeadline, _ := leaderCtx.Deadline()
+	laterDeadline, _ := laterCtx.Deadline()
+
+	type deadline struct {
+		d  time.Time
+		ok bool
+	}
+	check := make(chan struct{})
+	seen := make(chan deadline)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.DoContext(leaderCtx, "key", func(ctx context.Context) (interface{}, error) {
+			for range check {
+				d, ok := ctx.Deadline()
+				seen <- deadline{d, ok}
+			}
+			return "bar", nil
+		})
+	}()
+	for g.InFlight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	expect := func(want time.Time, wantOK bool) {
+		t.Helper()
+		check <- struct{}{}
+		if got := <-seen; !got.d.Equal(want) || got.ok != wantOK {
+			t.Fatalf("fn deadline = %v, %v, want %v, %v", got.d, got.ok, want, wantOK)
+		}
+	}
+	expect(leaderDeadline, true)
+
+	join := func(ctx context.Context) {
+		go g.DoContext(ctx, "key", func(context.Context) (interface{}, error) {
+			t.Error("fn should not be called for a duplicate key")
+			return nil, nil
+		})
+	}
+	join(laterCtx)
+	waitFor := func(want time.Time, wantOK bool) {
+		t.Helper()
+		for i := 0; ; i++ {
+			check <- struct{}{}
+			got := <-seen
+			if got.d.Equal(want) && got.ok == wantOK {
+				return
+			}
+			if i == 100 {
+				t.Fatalf("fn deadline = %v, %v, want %v, %v", got.d, got.ok, want, wantOK)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitFor(laterDeadline, true)
+	join(leaderCtx) // 更早的截止时间不会提前 fn 的截止时间
+	time.Sleep(10 * time.Millisecond)
+	expect(laterDeadline, true)
+	join(context.Background())
+	waitFor(time.Time{}, false)
+	close(check)
+	<-done
+}
+
+// 只有一个等待者时，它的截止时间到达后 fn 的 ctx 也会结束
+func TestDoContextDeadlineExpires(t *testing.T) {
+	var g Group
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	fnErr := make(chan error, 1)
+	_, err, _ := g.DoContext(ctx, "key", func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		fnErr <- ctx.Err()
+		return nil, ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want DeadlineExceeded", err)
+	}
+	select {
+	case err := <-fnErr:
+		if err == nil {
+			t.Fatal("fn ctx ended without an error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("fn was not cancelled after the deadline")
+	}
+}
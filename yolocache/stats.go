@@ -0,0 +1,83 @@
+package yolocache
+
+import "sync/atomic"
+
+/*
+***********************Group的运行统计*********************************
+ */
+
+// groupStats 是Group内部使用的计数器，会被多个协程并发更新，所以全部使用原子操作
+type groupStats struct {
+	gets           atomic.Int64 // 所有Get请求，包括其他节点转发过来的
+	cacheHits      atomic.Int64 // mainCache或hotCache命中
+	negativeHits   atomic.Int64 // 负缓存命中，直接返回了ErrNotFound
+	peerLoads      atomic.Int64 // 从其他节点获取成功
+	peerErrors     atomic.Int64 // 与其他节点通信失败
+	loads          atomic.Int64 // 缓存未命中，进入load的次数 (gets - cacheHits)
+	loadsDeduped   atomic.Int64 // 进入load后，因singleflight复用了其他请求结果的次数
+	localLoads     atomic.Int64 // 调用回调函数获取源数据成功
+	localLoadErrs  atomic.Int64 // 调用回调函数获取源数据失败
+	serverRequests atomic.Int64 // 其他节点通过HTTP发来的请求
+	ringMismatches atomic.Int64 // 其他节点发来的请求中，哈希环摘要与本节点不同的次数
+	hedges         atomic.Int64 // 第一个节点超过 hedgeDelay 没有回复，发起了对冲请求的次数
+	hedgeWins      atomic.Int64 // 对冲请求比第一个节点先得到结果的次数
+}
+
+// Stats 是Group在某一时刻的统计快照
+type Stats struct {
+	Gets           int64 `json:"gets"`
+	CacheHits      int64 `json:"cache_hits"`
+	NegativeHits   int64 `json:"negative_hits"`
+	PeerLoads      int64 `json:"peer_loads"`
+	PeerErrors     int64 `json:"peer_errors"`
+	Loads          int64 `json:"loads"`
+	LoadsDeduped   int64 `json:"loads_deduped"`
+	LocalLoads     int64 `json:"local_loads"`
+	LocalLoadErrs  int64 `json:"local_load_errs"`
+	ServerRequests int64 `json:"server_requests"`
+	RingMismatches int64 `json:"ring_mismatches"`
+	Hedges         int64 `json:"hedges"`
+	HedgeWins      int64 `json:"hedge_wins"`
+}
+
+// Stats 返回Group当前的统计快照
+func (g *Group) Stats() Stats {
+	return Stats{
+		Gets:           g.stats.gets.Load(),
+		CacheHits:      g.stats.cacheHits.Load(),
+		NegativeHits:   g.stats.negativeHits.Load(),
+		PeerLoads:      g.stats.peerLoads.Load(),
+		PeerErrors:     g.stats.peerErrors.Load(),
+		Loads:          g.stats.loads.Load(),
+		LoadsDeduped:   g.stats.loadsDeduped.Load(),
+		LocalLoads:     g.stats.localLoads.Load(),
+		LocalLoadErrs:  g.stats.localLoadErrs.Load(),
+		ServerRequests: g.stats.serverRequests.Load(),
+		RingMismatches: g.stats.ringMismatches.Load(),
+		Hedges:         g.stats.hedges.Load(),
+		HedgeWins:      g.stats.hedgeWins.Load(),
+	}
+}
+
+// CacheType 表示Group中的哪一个缓存
+type CacheType int
+
+const (
+	MainCache     CacheType = iota + 1 // 存放本节点作为所有者的值
+	HotCache                           // 存放从其他节点获取到的热点值
+	NegativeCache                      // 存放数据源中不存在的key
+)
+
+// CacheStats 返回指定缓存的统计快照
+func (g *Group) CacheStats(which CacheType) CacheStats {
+	switch which {
+	case MainCache:
+		return g.mainCache.stats()
+	case HotCache:
+		return g.hotCache.stats()
+	case NegativeCache:
+		return g.negCache.stats()
+	default:
+		return CacheStats{}
+	}
+}
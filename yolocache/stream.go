@@ -0,0 +1,137 @@
+package yolocache
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+/*
+***********************大值的流式传输*********************************
+普通的 Get 把整个值编码进一个 pb.Response：服务端 proto.Marshal 时拷贝一次，
+客户端 io.ReadAll 不知道值有多大，只能不断扩容，再 proto.Unmarshal 拷贝一次，几MB的值会造成很大的内存分配抖动。
+
+请求方在 Accept 中带上 streamContentType，服务端发现值不小于 streamThreshold 时改用下面的格式，
+直接从缓存中的切片分段写出，不做任何拷贝：
+
+	| 8字节：值的总长度 | 4字节：分片长度 n | n字节的数据 | ... | 4字节：0 |
+
+客户端先读到总长度，一次分配好内存，再依次读入每个分片，最后的0长度分片用来确认值是完整的。
+旧版本的节点不认识这个 Accept，仍然返回 pb.Response，客户端根据 Content-Type 区分，所以新旧节点可以混合部署。
+错误（不存在、回调函数失败、过载）仍然使用 pb.Response。
+*/
+
+const (
+	streamContentType      = "application/x-yolocache-stream"
+	streamEncodingHeader   = "X-Yolocache-Encoding" // 流式传输时值的压缩编码，相当于 Response.encoding
+	defaultStreamThreshold = 64 << 10               // 不小于64KB的值使用流式传输
+	streamChunkSize        = 32 << 10
+	// 根据对方发来的总长度预先分配内存的上限，超过时边读边扩容，避免错误的长度导致一次巨大的分配
+	maxStreamPrealloc = 64 << 20
+	// 默认接受的最大值，流式传输的总长度和解压后的大小都不能超过它
+	defaultMaxValueSize = 64 << 20
+)
+
+// WithMaxValueSize 设置从其他节点接受的最大值（流式传输的总长度、解压后的大小），n <= 0 时使用默认的64MB。
+// 超过时返回 ErrValueTooLarge 并回退到本地加载，它不算节点的失败
+func WithMaxValueSize(n int64) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		if n > 0 {
+			p.maxValueSize = n
+		}
+	}
+}
+
+// WithStreamThreshold 设置使用流式传输的最小值大小，n < 0 表示从不使用，默认为64KB
+func WithStreamThreshold(n int) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		p.streamThreshold = n
+	}
+}
+
+// acceptsStream 判断请求方是否支持流式传输
+func acceptsStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), streamContentType)
+}
+
+// writeStream 按上面的格式写出b
+func writeStream(w io.Writer, b []byte) error {
+	var hdr [8]byte
+	binary.BigEndian.PutUint64(hdr[:], uint64(len(b)))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	for len(b) > 0 {
+		n := len(b)
+		if n > streamChunkSize {
+			n = streamChunkSize
+		}
+		binary.BigEndian.PutUint32(hdr[:4], uint32(n))
+		if _, err := w.Write(hdr[:4]); err != nil {
+			return err
+		}
+		if _, err := w.Write(b[:n]); err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	binary.BigEndian.PutUint32(hdr[:4], 0)
+	_, err := w.Write(hdr[:4])
+	return err
+}
+
+// errShortStream 表示流在结束标记之前就断开了，或者分片的总长度与声明的不一致
+var errShortStream = errors.New("yolocache: truncated value stream")
+
+// readStream 读取 writeStream 写出的值，只分配一次内存，总长度超过 limit 时返回 ErrValueTooLarge
+func readStream(r io.Reader, limit int64) ([]byte, error) {
+	br := bufio.NewReader(r)
+	var hdr [8]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, fmt.Errorf("reading stream header: %w", err)
+	}
+	total := binary.BigEndian.Uint64(hdr[:])
+	if total > uint64(limit) {
+		return nil, fmt.Errorf("%w: value stream of %d bytes exceeds %d", ErrValueTooLarge, total, limit)
+	}
+	prealloc := total
+	if prealloc > maxStreamPrealloc {
+		prealloc = maxStreamPrealloc
+	}
+	b := make([]byte, 0, prealloc)
+	for {
+		if _, err := io.ReadFull(br, hdr[:4]); err != nil {
+			return nil, errShortStream
+		}
+		n := binary.BigEndian.Uint32(hdr[:4])
+		if n == 0 {
+			break
+		}
+		if uint64(len(b))+uint64(n) > total {
+			return nil, errShortStream
+		}
+		start := len(b)
+		b = append(b, make([]byte, n)...)
+		if _, err := io.ReadFull(br, b[start:]); err != nil {
+			return nil, errShortStream
+		}
+	}
+	if uint64(len(b)) != total {
+		return nil, errShortStream
+	}
+	return b, nil
+}
+
+// GetReader 与 GetContext 相同，但以 io.Reader 的形式返回值，不拷贝缓存中的数据
+func (g *Group) GetReader(ctx context.Context, key string) (io.Reader, error) {
+	view, err := g.GetContext(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return view.Reader(), nil
+}
@@ -0,0 +1,255 @@
+package test
+
+import (
+	"YoloCache/yolocache"
+	pb "YoloCache/yolocache/yolocachepb"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// jsonValue 返回一个容易压缩的、类似JSON的值
+func jsonValue(n int) []byte {
+	var b bytes.Buffer
+	for i := 0; b.Len() < n; i++ {
+		fmt.Fprintf(&b, `{"id":%d,"name":"student-%d","score":%d},`, i, i, i%100)
+	}
+	return b.Bytes()
+}
+
+// compressionPeers 创建一对节点，返回 client Group 和服务端实际写出的字节数
+func compressionPeers(t *testing.T, name string, value []byte, serverOpts, clientOpts []yolocache.HTTPPoolOption) (*yolocache.Group, *atomic.Int64, *atomic.Value) {
+	getter := yolocache.GetterFunc(func(key string) ([]byte, error) { return value, nil })
+	client := yolocache.NewGroup(name, 8<<20, getter)
+	yolocache.NewGroup(name, 8<<20, getter)
+	var written atomic.Int64
+	var encoding atomic.Value
+	encoding.Store("")
+	server := yolocache.NewHTTPPool("http://server", serverOpts...)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cw := &countingWriter{ResponseWriter: w}
+		server.ServeHTTP(cw, r)
+		written.Add(cw.n)
+		encoding.Store(w.Header().Get("X-Yolocache-Encoding"))
+	}))
+	t.Cleanup(srv.Close)
+	pool := yolocache.NewHTTPPool("http://client", clientOpts...)
+	pool.Set(srv.URL)
+	client.RegisterPeers(pool)
+	return client, &written, &encoding
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.n += int64(len(b))
+	return w.ResponseWriter.Write(b)
+}
+
+func TestCompression(t *testing.T) {
+	value := jsonValue(32 << 10)
+	on := []yolocache.HTTPPoolOption{yolocache.WithCompression(0)}
+	for _, tc := range []struct {
+		name               string
+		server, client     []yolocache.HTTPPoolOption
+		wantCompressedWire bool
+	}{
+		{"both", on, on, true},
+		// 新旧版本混合部署：只要有一方没有开启，就传输原始的值
+		{"old server", nil, on, false},
+		{"old client", on, nil, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client, written, _ := compressionPeers(t, "compress-"+strings.ReplaceAll(tc.name, " ", "-"), value, tc.server, tc.client)
+			v, err := client.Get("k")
+			if err != nil || !bytes.Equal(v.ByteSlice(), value) {
+				t.Fatalf("Get = %d bytes, %v", v.Len(), err)
+			}
+			if compressed := written.Load() < int64(len(value))/4; compressed != tc.wantCompressedWire {
+				t.Fatalf("server wrote %d bytes for a %d byte value, compressed = %v, want %v",
+					written.Load(), len(value), compressed, tc.wantCompressedWire)
+			}
+		})
+	}
+}
+
+func TestCompressionThreshold(t *testing.T) {
+	value := jsonValue(4 << 10)
+	on := []yolocache.HTTPPoolOption{yolocache.WithCompression(len(value) + 1)}
+	client, written, _ := compressionPeers(t, "compress-threshold", value, on, on)
+	if v, err := client.Get("k"); err != nil || v.Len() != len(value) {
+		t.Fatalf("Get = %d bytes, %v", v.Len(), err)
+	}
+	if written.Load() < int64(len(value)) {
+		t.Fatalf("a value below the threshold was compressed")
+	}
+}
+
+func TestCompressedStream(t *testing.T) {
+	value := jsonValue(2 << 20)
+	server := []yolocache.HTTPPoolOption{yolocache.WithCompression(0), yolocache.WithStreamThreshold(1)}
+	client, written, encoding := compressionPeers(t, "compress-stream", value, server, []yolocache.HTTPPoolOption{yolocache.WithCompression(0)})
+	v, err := client.Get("k")
+	if err != nil || !bytes.Equal(v.ByteSlice(), value) {
+		t.Fatalf("Get = %d bytes, %v", v.Len(), err)
+	}
+	if encoding.Load() != "gzip" || written.Load() > int64(len(value))/4 {
+		t.Fatalf("stream encoding %q, %d bytes on the wire", encoding.Load(), written.Load())
+	}
+}
+
+// runLength 是测试用的编码：把同一个字节重复的值编码为 [字节, 4字节长度]
+type runLength struct{}
+
+func (runLength) Name() string { return "test-rle" }
+
+func (runLength) Encode(b []byte) ([]byte, error) {
+	if len(b) == 0 || !bytes.Equal(b, bytes.Repeat(b[:1], len(b))) {
+		return b, nil // 不能编码时返回原样，不会比原来更小，所以不会被使用
+	}
+	out := make([]byte, 5)
+	out[0] = b[0]
+	binary.BigEndian.PutUint32(out[1:], uint32(len(b)))
+	return out, nil
+}
+
+func (runLength) Decode(b []byte, limit int64) ([]byte, error) {
+	if len(b) != 5 {
+		return nil, errors.New("bad rle value")
+	}
+	n := binary.BigEndian.Uint32(b[1:])
+	if int64(n) > limit {
+		return nil, fmt.Errorf("%w: rle value of %d bytes exceeds %d", yolocache.ErrValueTooLarge, n, limit)
+	}
+	return bytes.Repeat(b[:1], int(n)), nil
+}
+
+func TestRegisterCodec(t *testing.T) {
+	yolocache.RegisterCodec(runLength{})
+	value := bytes.Repeat([]byte("x"), 10000)
+	on := []yolocache.HTTPPoolOption{yolocache.WithCompression(0, "test-rle", "gzip")}
+	client, written, _ := compressionPeers(t, "compress-rle", value, on, on)
+	if v, err := client.Get("k"); err != nil || !bytes.Equal(v.ByteSlice(), value) {
+		t.Fatalf("Get = %d bytes, %v", v.Len(), err)
+	}
+	if n := written.Load(); n > 32 {
+		t.Fatalf("server wrote %d bytes, the preferred codec was not used", n)
+	}
+}
+
+// 服务端只使用双方都配置了的编码，即使请求方更偏好的编码在服务端也注册过
+func TestCompressionCommonCodec(t *testing.T) {
+	yolocache.RegisterCodec(runLength{})
+	value := bytes.Repeat([]byte("x"), 10000)
+	server := []yolocache.HTTPPoolOption{yolocache.WithCompression(0, "gzip"), yolocache.WithStreamThreshold(1)}
+	client := []yolocache.HTTPPoolOption{yolocache.WithCompression(0, "test-rle", "gzip")}
+	g, _, encoding := compressionPeers(t, "compress-common", value, server, client)
+	if v, err := g.Get("k"); err != nil || !bytes.Equal(v.ByteSlice(), value) {
+		t.Fatalf("Get = %d bytes, %v", v.Len(), err)
+	}
+	if e := encoding.Load(); e != "gzip" {
+		t.Fatalf("server used %q, want the only codec both sides accept", e)
+	}
+}
+
+// 解压后的值超过 WithMaxValueSize 时回退到本地加载，这是值本身的问题，不重试，也不算节点的失败
+func TestCompressionDecodeLimit(t *testing.T) {
+	value := jsonValue(64 << 10)
+	client := yolocache.NewGroup("compress-limit", 2<<10, yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("local"), nil
+	}))
+	// 缓存容量与值的大小无关，小容量的 Group 也能收到比容量大的值
+	yolocache.NewGroup("compress-limit", 2<<10, yolocache.GetterFunc(func(key string) ([]byte, error) {
+		if key == "big" {
+			return value, nil
+		}
+		return jsonValue(8 << 10), nil
+	}))
+	var requests atomic.Int64
+	server := yolocache.NewHTTPPool("http://server", yolocache.WithCompression(0))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		server.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	pool := yolocache.NewHTTPPool("http://client", yolocache.WithCompression(0), yolocache.WithMaxValueSize(32<<10),
+		yolocache.WithRetry(3, time.Millisecond), yolocache.WithCircuitBreaker(1, time.Minute))
+	pool.Set(srv.URL)
+	client.RegisterPeers(pool)
+
+	if v, err := client.Get("big"); err != nil || v.String() != "local" {
+		t.Fatalf("Get(big) = %d bytes, %v, want the oversized value to be rejected", v.Len(), err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("server got %d requests, an oversized value must not be retried", n)
+	}
+	// 熔断器没有因此打开，比缓存容量大、但没有超过上限的值仍然从所有者获取
+	if v, err := client.Get("small"); err != nil || !bytes.Equal(v.ByteSlice(), jsonValue(8<<10)) {
+		t.Fatalf("Get(small) = %d bytes, %v, want the owner's value", v.Len(), err)
+	}
+}
+
+func TestGRPCCompression(t *testing.T) {
+	value := jsonValue(64 << 10)
+	getter := yolocache.GetterFunc(func(key string) ([]byte, error) { return value, nil })
+	client := yolocache.NewGroup("grpccompress", 8<<20, getter)
+	server := yolocache.NewGroup("grpccompress", 8<<20, getter)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	go yolocache.NewGRPCPool(lis.Addr().String(), yolocache.WithGRPCCompression(0)).Serve(lis)
+	// 在解压之前检查服务端回复的编码
+	var compressed atomic.Int64
+	inspect := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		switch r := reply.(type) {
+		case *pb.Response:
+			if r.GetEncoding() == "gzip" {
+				compressed.Add(1)
+			}
+		case *pb.BatchResponse:
+			for _, res := range r.GetResponses() {
+				if res.GetEncoding() == "gzip" {
+					compressed.Add(1)
+				}
+			}
+		}
+		return err
+	}
+	pool := yolocache.NewGRPCPool("client", yolocache.WithGRPCCompression(0),
+		yolocache.WithDialOptions(grpc.WithUnaryInterceptor(inspect)))
+	pool.Set(lis.Addr().String())
+	client.RegisterPeers(pool)
+
+	if v, err := client.Get("k"); err != nil || !bytes.Equal(v.ByteSlice(), value) {
+		t.Fatalf("Get = %d bytes, %v", v.Len(), err)
+	}
+	results := client.GetMulti([]string{"a", "b"})
+	for _, r := range results {
+		if r.Err != nil || !bytes.Equal(r.Value.ByteSlice(), value) {
+			t.Fatalf("GetMulti = %d bytes, %v", r.Value.Len(), r.Err)
+		}
+	}
+	if s := server.Stats(); s.ServerRequests != 3 {
+		t.Fatalf("server stats = %+v", s)
+	}
+	if n := compressed.Load(); n != 3 {
+		t.Fatalf("%d of 3 values were compressed on the wire", n)
+	}
+}
@@ -2,9 +2,12 @@ package test
 
 import (
 	"YoloCache/yolocache"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"testing"
+	"time"
 )
 
 var db = map[string]string{
@@ -49,3 +52,101 @@ func TestGet(t *testing.T) {
 		t.Fatalf("the value of unknow should be empty, but %s got", view)
 	}
 }
+
+// 慢数据源在调用方超时后应当随ctx返回，而不是一直阻塞
+func TestGetContextDeadline(t *testing.T) {
+	g := yolocache.NewGroup("slow", 2<<10, yolocache.GetterCtxFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := g.GetContext(ctx, "Tom"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+// 过期后的缓存值应当被视为未命中，重新调用回调函数
+func TestGetExpire(t *testing.T) {
+	loads := 0
+	g := yolocache.NewGroup("ttl", 2<<10, yolocache.GetterTTLFunc(
+		func(ctx context.Context, key string) ([]byte, time.Duration, error) {
+			loads++
+			if key == "Sam" {
+				return []byte(db[key]), -1, nil // 永不过期
+			}
+			return []byte(db[key]), 0, nil // 使用Group默认的ttl
+		}), yolocache.WithTTL(20*time.Millisecond))
+
+	for _, k := range []string{"Tom", "Sam", "Tom", "Sam"} {
+		if _, err := g.Get(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if loads != 2 {
+		t.Fatalf("expected 2 loads before expiry, got %d", loads)
+	}
+	time.Sleep(30 * time.Millisecond)
+	for _, k := range []string{"Tom", "Sam"} {
+		if _, err := g.Get(k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if loads != 3 {
+		t.Fatalf("expected only Tom to be reloaded after expiry, got %d loads", loads)
+	}
+}
+
+func TestStats(t *testing.T) {
+	g := yolocache.NewGroup("stats", 2<<10, yolocache.GetterFunc(
+		func(key string) ([]byte, error) {
+			if v, ok := db[key]; ok {
+				return []byte(v), nil
+			}
+			return nil, fmt.Errorf("%s not exist", key)
+		}))
+	g.Get("Tom")
+	g.Get("Tom")
+	g.Get("unknown")
+
+	want := yolocache.Stats{Gets: 3, CacheHits: 1, Loads: 2, LocalLoads: 1, LocalLoadErrs: 1}
+	if st := g.Stats(); st != want {
+		t.Fatalf("Stats() = %+v, want %+v", st, want)
+	}
+	if cs := g.CacheStats(yolocache.MainCache); cs.Items != 1 || cs.Gets != 3 || cs.Hits != 1 {
+		t.Fatalf("CacheStats(MainCache) = %+v", cs)
+	}
+}
+
+func TestNegativeCache(t *testing.T) {
+	loads := 0
+	g := yolocache.NewGroup("negative", 2<<10, yolocache.GetterFunc(
+		func(key string) ([]byte, error) {
+			loads++
+			return nil, fmt.Errorf("%s: %w", key, yolocache.ErrNotFound)
+		}), yolocache.WithNegativeCache(20*time.Millisecond, 1<<10))
+
+	for i := 0; i < 3; i++ {
+		if _, err := g.Get("unknown"); !errors.Is(err, yolocache.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+	}
+	if loads != 1 || g.Stats().NegativeHits != 2 {
+		t.Fatalf("expected 1 load and 2 negative hits, got %d loads, %+v", loads, g.Stats())
+	}
+	// 写入后负缓存失效
+	g.Set("unknown", []byte("1"))
+	if v, err := g.Get("unknown"); err != nil || v.String() != "1" {
+		t.Fatalf("Get after Set = %q, %v", v, err)
+	}
+	// 负缓存过期后重新询问数据源
+	g.Remove("unknown")
+	g.Get("unknown")
+	time.Sleep(30 * time.Millisecond)
+	g.Get("unknown")
+	if loads != 3 {
+		t.Fatalf("expected negative entry to expire, loads = %d", loads)
+	}
+}
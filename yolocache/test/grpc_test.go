@@ -0,0 +1,141 @@
+package test
+
+import (
+	"YoloCache/yolocache"
+	pb "YoloCache/yolocache/yolocachepb"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 与 newPeerGroups 相同，但节点间使用 gRPC 通信
+func newGRPCPeerGroups(t *testing.T, name string, getter yolocache.Getter, opts ...yolocache.GRPCPoolOption) (client, server *yolocache.Group) {
+	client = yolocache.NewGroup(name, 2<<10, getter)
+	server = yolocache.NewGroup(name, 2<<10, getter)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lis.Close() })
+	go yolocache.NewGRPCPool(lis.Addr().String()).Serve(lis)
+
+	pool := yolocache.NewGRPCPool("client", opts...)
+	pool.Set(lis.Addr().String())
+	client.RegisterPeers(pool)
+	return client, server
+}
+
+func TestGRPCPool(t *testing.T) {
+	loads := 0
+	client, _ := newGRPCPeerGroups(t, "grpc", yolocache.GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte(db[key]), nil
+	}))
+
+	if v, err := client.Get("Tom"); err != nil || v.String() != "630" {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+	if err := client.Set("Tom", []byte("700")); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := client.Get("Tom"); err != nil || v.String() != "700" {
+		t.Fatalf("Get after Set = %q, %v", v, err)
+	}
+	if err := client.Remove("Tom"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := client.Get("Tom"); err != nil || v.String() != "630" || loads != 2 {
+		t.Fatalf("Get after Remove = %q, %v, loads = %d", v, err, loads)
+	}
+}
+
+// 所有者节点的数据源很慢时，RPC应在超时后结束，client 随即回退到自己的数据源
+func TestGRPCPoolTimeout(t *testing.T) {
+	client, _ := newGRPCPeerGroups(t, "grpctimeout", yolocache.GetterCtxFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return []byte("local"), nil
+	}), yolocache.WithRPCTimeout(50*time.Millisecond))
+	// 覆盖全局注册表中的 server，让 gRPC 服务端使用慢数据源
+	yolocache.NewGroup("grpctimeout", 2<<10, yolocache.GetterCtxFunc(func(ctx context.Context, key string) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	v, err := client.GetContext(ctx, "Tom")
+	if err != nil || v.String() != "local" {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("RPC timeout not applied, Get took %v", elapsed)
+	}
+	if st := client.Stats(); st.PeerErrors != 1 {
+		t.Fatalf("expected 1 peer error, got %+v", st)
+	}
+}
+
+// 被 Set 移除的节点，调用方手里剩下的客户端不能再重新建立连接
+func TestGRPCPoolRemovedPeer(t *testing.T) {
+	yolocache.NewGroup("grpcremoved", 2<<10, yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte(db[key]), nil
+	}))
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lis.Close() })
+	go yolocache.NewGRPCPool(lis.Addr().String()).Serve(lis)
+
+	pool := yolocache.NewGRPCPool("client")
+	pool.Set(lis.Addr().String())
+	peer, ok := pool.PickPeer("Tom")
+	if !ok {
+		t.Fatal("expected the only peer to be picked")
+	}
+	get := func() error {
+		return peer.Get(context.Background(), &pb.Request{Group: "grpcremoved", Key: "Tom"}, &pb.Response{})
+	}
+	if err := get(); err != nil {
+		t.Fatal(err)
+	}
+	pool.Set()
+	if err := get(); err == nil {
+		t.Fatal("removed peer should not reconnect")
+	}
+}
+
+// 与 HTTPPool 相同，接收方拒绝转发次数超过1的请求
+func TestGRPCRejectForwardedTwice(t *testing.T) {
+	loads := 0
+	yolocache.NewGroup("grpchops", 2<<10, yolocache.GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte(db[key]), nil
+	}))
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lis.Close() })
+	go yolocache.NewGRPCPool(lis.Addr().String()).Serve(lis)
+
+	pool := yolocache.NewGRPCPool("client")
+	pool.Set(lis.Addr().String())
+	peer, _ := pool.PickPeer("Tom")
+	ctx := context.Background()
+	err = peer.Get(ctx, &pb.Request{Group: "grpchops", Key: "Tom", Hops: 2}, &pb.Response{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("err = %v, want InvalidArgument", err)
+	}
+	err = peer.(yolocache.BatchGetter).GetBatch(ctx, &pb.BatchRequest{Group: "grpchops", Keys: []string{"Tom"}, Hops: 2}, &pb.BatchResponse{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("batch err = %v, want InvalidArgument", err)
+	}
+	if loads != 0 {
+		t.Fatalf("rejected requests caused %d loads", loads)
+	}
+}
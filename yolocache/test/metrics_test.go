@@ -0,0 +1,96 @@
+package test
+
+import (
+	"YoloCache/yolocache"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	client, _ := newPeerGroups(t, "metrics", yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte(db[key]), nil
+	}))
+	if _, err := client.Get("Tom"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 全局注册表中的是 server，请求经由 client 转发给它
+	rec := httptest.NewRecorder()
+	yolocache.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE yolocache_gets_total counter\n",
+		`yolocache_server_requests_total{group="metrics"} 1`,
+		`yolocache_cache_items{group="metrics",cache="main"} 1`,
+		`yolocache_singleflight_inflight{group="metrics"} 0`,
+		"# TYPE yolocache_peer_rpc_duration_seconds histogram\n",
+		`yolocache_peer_rpc_duration_seconds_bucket{peer="http://127.0.0.1:`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+}
+
+// 节点被移除后，它的耗时直方图也从 /metrics 中消失
+func TestMetricsDropRemovedPeer(t *testing.T) {
+	const peer = "http://removed-peer.invalid:8001"
+	metrics := func() string {
+		rec := httptest.NewRecorder()
+		yolocache.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+		return rec.Body.String()
+	}
+	label := `peer="` + peer + `"`
+
+	pool := yolocache.NewHTTPPool("http://self")
+	pool.Set("http://self", peer)
+	if !strings.Contains(metrics(), label) {
+		t.Fatalf("metrics output missing %s", label)
+	}
+	pool.RemovePeers(peer)
+	if strings.Contains(metrics(), label) {
+		t.Fatalf("metrics output still has %s after it was removed", label)
+	}
+
+	// 两个 Pool 共用同一个节点时，只有都移除后才删除
+	a, b := yolocache.NewHTTPPool("http://a"), yolocache.NewHTTPPool("http://b")
+	a.Set(peer)
+	b.Set(peer)
+	a.Set()
+	if !strings.Contains(metrics(), label) {
+		t.Fatalf("metrics output lost %s while another pool still uses it", label)
+	}
+	b.Set()
+	if strings.Contains(metrics(), label) {
+		t.Fatalf("metrics output still has %s after every pool removed it", label)
+	}
+}
+
+// 其他节点转发过来、还在加载中的请求也算在 yolocache_singleflight_inflight 中
+func TestMetricsInflightPeerLoads(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	client, _ := newPeerGroups(t, "metrics-inflight", yolocache.GetterFunc(func(key string) ([]byte, error) {
+		close(started)
+		<-release
+		return []byte(db[key]), nil
+	}))
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Get("Tom")
+		done <- err
+	}()
+	<-started
+
+	// 全局注册表中的是 server，它正在为 client 转发过来的请求加载
+	rec := httptest.NewRecorder()
+	yolocache.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	close(release)
+	if want := `yolocache_singleflight_inflight{group="metrics-inflight"} 1`; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("metrics output missing %q", want)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
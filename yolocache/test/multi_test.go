@@ -0,0 +1,197 @@
+package test
+
+import (
+	"YoloCache/yolocache"
+	pb "YoloCache/yolocache/yolocachepb"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// 数据源中 missing 开头的key不存在，其他key的值带上加载它的节点
+func multiGetter(node string) yolocache.Getter {
+	return yolocache.GetterFunc(func(key string) ([]byte, error) {
+		if strings.HasPrefix(key, "missing") {
+			return nil, yolocache.ErrNotFound
+		}
+		return []byte(node + "-" + key), nil
+	})
+}
+
+func TestGetMulti(t *testing.T) {
+	client := yolocache.NewGroup("multi", 2<<10, multiGetter("client"))
+	yolocache.NewGroup("multi", 2<<10, multiGetter("peer"))
+	var batches, singles atomic.Int64
+	server := yolocache.NewHTTPPool("http://server")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/_batch") {
+			batches.Add(1)
+		} else {
+			singles.Add(1)
+		}
+		server.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	pool := yolocache.NewHTTPPool("http://client")
+	pool.Set("http://client", srv.URL)
+	client.RegisterPeers(pool)
+
+	// 先缓存一个由本节点负责的key
+	keys := []string{"missing-1"}
+	for i := 0; i < 50; i++ {
+		keys = append(keys, fmt.Sprint("key", i))
+	}
+	keys = append(keys, "key0") // 重复的key
+	if _, err := client.Get("key1"); err != nil {
+		t.Fatal(err)
+	}
+	want := func(key string) string {
+		if _, remote := pool.PickPeer(key); remote {
+			return "peer-" + key
+		}
+		return "client-" + key
+	}
+
+	results := client.GetMulti(keys)
+	if len(results) != len(keys) {
+		t.Fatalf("got %d results for %d keys", len(results), len(keys))
+	}
+	for i, key := range keys {
+		r := results[i]
+		if key == "missing-1" {
+			if !errors.Is(r.Err, yolocache.ErrNotFound) {
+				t.Fatalf("%s: err = %v, want ErrNotFound", key, r.Err)
+			}
+			continue
+		}
+		if r.Err != nil || r.Value.String() != want(key) {
+			t.Fatalf("%s = %q, %v, want %q", key, r.Value.String(), r.Err, want(key))
+		}
+	}
+	if b, s := batches.Load(), singles.Load(); b != 1 || s > 1 {
+		t.Fatalf("server got %d batch and %d single requests, want 1 batch", b, s)
+	}
+}
+
+func TestGetMultiGRPC(t *testing.T) {
+	client, server := newGRPCPeerGroups(t, "grpcmulti", multiGetter("node"))
+	keys := []string{"a", "b", "missing", "c"}
+	results := client.GetMulti(keys)
+	for i, key := range keys {
+		r := results[i]
+		if key == "missing" {
+			if !errors.Is(r.Err, yolocache.ErrNotFound) {
+				t.Fatalf("%s: err = %v, want ErrNotFound", key, r.Err)
+			}
+			continue
+		}
+		if r.Err != nil || r.Value.String() != "node-"+key {
+			t.Fatalf("%s = %q, %v", key, r.Value.String(), r.Err)
+		}
+	}
+	if s := server.Stats(); s.ServerRequests != int64(len(keys)) {
+		t.Fatalf("server stats = %+v, want all keys served by the peer", s)
+	}
+	if s := client.Stats(); s.PeerLoads != int64(len(keys)) || s.LocalLoads != 0 {
+		t.Fatalf("client stats = %+v", s)
+	}
+}
+
+// 批量请求中的每个key各占一个 WithMaxInflight 名额，超出的key回复过载，由调用方按 Get 的流程重新加载
+func TestGetMultiInflightPerKey(t *testing.T) {
+	client := yolocache.NewGroup("multiinflight", 2<<10, multiGetter("client"))
+	yolocache.NewGroup("multiinflight", 2<<10, multiGetter("peer"))
+	var singles atomic.Int64
+	server := yolocache.NewHTTPPool("http://server", yolocache.WithMaxInflight(2))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/_batch") {
+			singles.Add(1)
+		}
+		server.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	pool := yolocache.NewHTTPPool("http://client")
+	pool.Set(srv.URL)
+	client.RegisterPeers(pool)
+
+	keys := []string{"a", "b", "c", "d"}
+	results := client.GetMulti(keys)
+	for i, key := range keys {
+		if r := results[i]; r.Err != nil || r.Value.String() != "peer-"+key {
+			t.Fatalf("%s = %q, %v", key, r.Value.String(), r.Err)
+		}
+	}
+	// 批量请求只拿到两个名额，另外两个key过载后各自单独重新请求
+	if n := singles.Load(); n != 2 {
+		t.Fatalf("%d keys were retried one by one, want 2", n)
+	}
+}
+
+// 已经有 Get 在加载的key不会再放进批量请求，而是等待那次加载的结果
+func TestGetMultiJoinsInflightLoad(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	client := yolocache.NewGroup("multijoin", 2<<10, multiGetter("client"))
+	yolocache.NewGroup("multijoin", 2<<10, yolocache.GetterFunc(func(key string) ([]byte, error) {
+		if key == "slow" {
+			started <- struct{}{}
+			<-release
+		}
+		return []byte("peer-" + key), nil
+	}))
+	var requested atomic.Int64 // 服务端收到的key的个数
+	server := yolocache.NewHTTPPool("http://server")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/_batch") {
+			body, _ := io.ReadAll(r.Body)
+			in := &pb.BatchRequest{}
+			if err := proto.Unmarshal(body, in); err != nil {
+				t.Error(err)
+			}
+			requested.Add(int64(len(in.GetKeys())))
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		} else {
+			requested.Add(1)
+		}
+		server.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	pool := yolocache.NewHTTPPool("http://client")
+	pool.Set(srv.URL)
+	client.RegisterPeers(pool)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if v, err := client.Get("slow"); err != nil || v.String() != "peer-slow" {
+			t.Errorf("Get(slow) = %q, %v", v.String(), err)
+		}
+	}()
+	<-started
+	go func() {
+		time.Sleep(20 * time.Millisecond) // 让 GetMulti 先加入进行中的加载
+		close(release)
+	}()
+	results := client.GetMulti([]string{"slow", "fast"})
+	for i, key := range []string{"slow", "fast"} {
+		if r := results[i]; r.Err != nil || r.Value.String() != "peer-"+key {
+			t.Fatalf("%s = %q, %v", key, r.Value.String(), r.Err)
+		}
+	}
+	<-done
+	if n := requested.Load(); n != 2 {
+		t.Fatalf("server was asked for %d keys, want 2 (slow once, fast once)", n)
+	}
+	if s := client.Stats(); s.LoadsDeduped != 1 {
+		t.Fatalf("client deduped %d loads, want 1", s.LoadsDeduped)
+	}
+}
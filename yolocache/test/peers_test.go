@@ -0,0 +1,875 @@
+package test
+
+import (
+	"YoloCache/yolocache"
+	pb "YoloCache/yolocache/yolocachepb"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// 单进程内模拟两个节点：client 节点的 HTTPPool 只认识 server 一个节点，所以所有 key 都会被转发给 server。
+// NewGroup 会用同名的新 Group 覆盖全局注册表，因此先创建的 client 与后创建的 server 是两个独立的 Group，
+// server 端的 ServeHTTP 通过 GetGroup 拿到的是 server。
+func newPeerGroups(t *testing.T, name string, getter yolocache.Getter, opts ...yolocache.GroupOption) (client, server *yolocache.Group) {
+	client = yolocache.NewGroup(name, 2<<10, getter, opts...)
+	server = yolocache.NewGroup(name, 2<<10, getter, opts...)
+	srv := httptest.NewServer(yolocache.NewHTTPPool("http://server"))
+	t.Cleanup(srv.Close)
+	pool := yolocache.NewHTTPPool("http://client")
+	pool.Set(srv.URL)
+	client.RegisterPeers(pool)
+	return client, server
+}
+
+func TestSetRemoveViaPeer(t *testing.T) {
+	loads := 0
+	client, _ := newPeerGroups(t, "setremove", yolocache.GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte("from-db"), nil
+	}))
+
+	if err := client.Set("Tom", []byte("700")); err != nil {
+		t.Fatal(err)
+	}
+	// 写入落到了 server 的 mainCache 中，读取时不应再调用回调函数
+	if v, err := client.Get("Tom"); err != nil || v.String() != "700" || loads != 0 {
+		t.Fatalf("Get after Set = %q, %v, loads = %d", v, err, loads)
+	}
+
+	if err := client.Remove("Tom"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := client.Get("Tom"); err != nil || v.String() != "from-db" || loads != 1 {
+		t.Fatalf("Get after Remove = %q, %v, loads = %d", v, err, loads)
+	}
+}
+
+func TestHotCache(t *testing.T) {
+	client, server := newPeerGroups(t, "hot", yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte(db[key]), nil
+	}), yolocache.WithHotCache(0.5, 1, 50*time.Millisecond)) // 准入概率为1，每次从其他节点获取到的值都会进入hotCache
+
+	if v, err := client.Get("Tom"); err != nil || v.String() != "630" {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+	if n := client.CacheStats(yolocache.HotCache).Items; n != 1 {
+		t.Fatalf("hot cache holds %d items, want 1", n)
+	}
+	// 直接修改所有者节点上的值，hotCache中的副本过期后，client 应当读到新值
+	if err := server.Set("Tom", []byte("700")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if v, _ := client.Get("Tom"); v.String() != "700" {
+		t.Fatalf("expected hot copy to expire, got %q", v)
+	}
+	// 通过 client 写入会立即清掉它自己的 hotCache
+	if err := client.Set("Tom", []byte("800")); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := client.Get("Tom"); v.String() != "800" {
+		t.Fatalf("expected hot cache to be invalidated by Set, got %q", v)
+	}
+}
+
+// hotCache 默认关闭，从其他节点获取到的值不会在本节点留下副本
+func TestHotCacheDisabledByDefault(t *testing.T) {
+	client, server := newPeerGroups(t, "nohot", yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte(db[key]), nil
+	}))
+	if v, err := client.Get("Tom"); err != nil || v.String() != "630" {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+	if err := server.Set("Tom", []byte("700")); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := client.Get("Tom"); v.String() != "700" {
+		t.Fatalf("expected a fresh read from the owner, got %q", v)
+	}
+}
+
+func TestStatsEndpoint(t *testing.T) {
+	g := yolocache.NewGroup("statsendpoint", 2<<10, yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	}))
+	g.Get("Tom")
+	srv := httptest.NewServer(yolocache.NewHTTPPool("http://server"))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/_yolocache/_stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var out map[string]struct {
+		Stats     yolocache.Stats      `json:"stats"`
+		MainCache yolocache.CacheStats `json:"main_cache"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if st := out["statsendpoint"]; st.Stats.Gets != 1 || st.MainCache.Items != 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+// 所有者返回的不存在和回调函数失败都应原样传回调用方，调用方不能再回退到本地加载
+func TestPeerErrorProtocol(t *testing.T) {
+	loads := 0
+	client, _ := newPeerGroups(t, "errproto", yolocache.GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		if key == "broken" {
+			return nil, errors.New("db is down")
+		}
+		return nil, fmt.Errorf("%s: %w", key, yolocache.ErrNotFound)
+	}))
+
+	if _, err := client.Get("unknown"); !errors.Is(err, yolocache.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := client.Get("broken"); !errors.Is(err, yolocache.ErrGetterFailed) {
+		t.Fatalf("expected ErrGetterFailed, got %v", err)
+	}
+	if loads != 2 {
+		t.Fatalf("client should not fall back to its own getter, loads = %d", loads)
+	}
+}
+
+// 所有者的加载超时不是确定的结果，调用方应回退到本地加载，而不是把超时返回给用户
+func TestPeerLoadCanceled(t *testing.T) {
+	client := yolocache.NewGroup("canceled", 2<<10, yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("local"), nil
+	}))
+	yolocache.NewGroup("canceled", 2<<10, yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return nil, fmt.Errorf("query %s: %w", key, context.DeadlineExceeded)
+	}))
+	srv := httptest.NewServer(yolocache.NewHTTPPool("http://server"))
+	defer srv.Close()
+	pool := yolocache.NewHTTPPool("http://client")
+	pool.Set(srv.URL)
+	client.RegisterPeers(pool)
+
+	if v, err := client.Get("Tom"); err != nil || v.String() != "local" {
+		t.Fatalf("expected fallback to local load, got %q, %v", v, err)
+	}
+}
+
+// 所有者过载时回复 OVERLOADED，调用方回退到本地加载
+func TestPeerOverloaded(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	client := yolocache.NewGroup("overload", 2<<10, yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("local"), nil
+	}))
+	yolocache.NewGroup("overload", 2<<10, yolocache.GetterFunc(func(key string) ([]byte, error) {
+		started <- struct{}{}
+		<-release
+		return []byte("remote"), nil
+	}))
+	srv := httptest.NewServer(yolocache.NewHTTPPool("http://server", yolocache.WithMaxInflight(1)))
+	defer srv.Close()
+	pool := yolocache.NewHTTPPool("http://client")
+	pool.Set(srv.URL)
+	client.RegisterPeers(pool)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.Get("Tom")
+	}()
+	<-started // 第一个请求占住了唯一的名额
+	if v, err := client.Get("Jack"); err != nil || v.String() != "local" {
+		t.Fatalf("expected fallback to local load, got %q, %v", v, err)
+	}
+	close(release)
+	<-done
+}
+
+// 所有者确认不存在后，调用方也记住这个结果，之后不再询问所有者
+func TestNegativeCacheViaPeer(t *testing.T) {
+	client, server := newPeerGroups(t, "negpeer", yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return nil, yolocache.ErrNotFound
+	}), yolocache.WithNegativeCache(time.Minute, 1<<10))
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.Get("unknown"); !errors.Is(err, yolocache.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+	}
+	if n := server.Stats().ServerRequests; n != 1 {
+		t.Fatalf("expected the owner to be asked once, got %d", n)
+	}
+}
+
+func TestDynamicPeers(t *testing.T) {
+	pool := yolocache.NewHTTPPool("http://a")
+	pool.AddPeers("http://a", "http://b")
+	if v := pool.Version(); v != 1 {
+		t.Fatalf("Version = %d, want 1", v)
+	}
+	// 找一个属于 b 的key
+	var key string
+	var owner yolocache.PeerGetter
+	for i := 0; owner == nil; i++ {
+		key = fmt.Sprintf("key%d", i)
+		owner, _ = pool.PickPeer(key)
+	}
+
+	// 加入新节点后，仍属于 b 的key应复用原来的 httpGetter
+	pool.AddPeers("http://c")
+	pool.AddPeers("http://c") // 重复添加不改变版本号
+	if v := pool.Version(); v != 2 {
+		t.Fatalf("Version = %d, want 2", v)
+	}
+	reused := false
+	for i := 0; i < 100 && !reused; i++ {
+		key = fmt.Sprintf("key%d", i)
+		p, _ := pool.PickPeer(key)
+		reused = p == owner
+	}
+	if !reused {
+		t.Fatalf("existing peer getter should be reused")
+	}
+
+	pool.RemovePeers("http://b")
+	if p, _ := pool.PickPeer(key); p == owner {
+		t.Fatalf("removed peer should no longer be picked")
+	}
+	pool.Set("http://a", "http://c") // 与当前节点列表相同，不改变版本号
+	if v, peers := pool.Version(), pool.Peers(); v != 3 || fmt.Sprint(peers) != "[http://a http://c]" {
+		t.Fatalf("Version = %d, Peers = %v", v, peers)
+	}
+}
+
+func TestWeightedPeers(t *testing.T) {
+	pool := yolocache.NewHTTPPool("http://a")
+	pool.SetWeighted(map[string]int{"http://a": 1, "http://b": 4})
+	remote := 0
+	for i := 0; i < 1000; i++ {
+		if _, ok := pool.PickPeer(fmt.Sprintf("key%d", i)); ok {
+			remote++
+		}
+	}
+	if remote < 650 || remote > 900 {
+		t.Fatalf("weight 4 peer owns %d/1000 keys, want about 800", remote)
+	}
+
+	// Set 保留已有节点的权重，只改权重时版本号也要变化
+	pool.Set("http://a", "http://b")
+	if v := pool.Version(); v != 1 {
+		t.Fatalf("Version = %d, want 1", v)
+	}
+	pool.AddWeightedPeers(map[string]int{"http://b": 1})
+	if v := pool.Version(); v != 2 {
+		t.Fatalf("Version = %d, want 2", v)
+	}
+}
+
+func TestBoundedLoads(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}))
+	defer fast.Close()
+
+	pool := yolocache.NewHTTPPool("http://self", yolocache.WithBoundedLoads(0.25))
+	// 先只加入 slow，拿到它的 PeerGetter，之后添加节点时已有的 PeerGetter 会被复用
+	pool.Set(slow.URL)
+	slowPeer, _ := pool.PickOwner("probe")
+	pool.AddPeers("http://self", fast.URL)
+	// 找一个属于 slow 的key
+	var key string
+	for i := 0; ; i++ {
+		key = fmt.Sprintf("key%d", i)
+		if p, ok := pool.PickPeer(key); ok && p == slowPeer {
+			break
+		}
+		if i > 1000 {
+			t.Fatalf("no key owned by the slow peer")
+		}
+	}
+
+	// slow 上有一个正在进行的请求，超过了上限，key 溢出到其他节点；写请求仍然发给所有者
+	done := make(chan error)
+	go func() {
+		done <- slowPeer.Get(context.Background(), &pb.Request{Group: "g", Key: key}, &pb.Response{})
+	}()
+	<-started
+	if p, _ := pool.PickPeer(key); p == slowPeer {
+		t.Fatalf("loaded peer should be skipped")
+	}
+	if p, _ := pool.PickOwner(key); p != slowPeer {
+		t.Fatalf("PickOwner should ignore loads")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := pool.PickPeer(key); p != slowPeer {
+		t.Fatalf("load should be released after the call")
+	}
+}
+
+// 所有者不可用时依次尝试其他副本，而不是直接回退到数据源
+func TestReplicaFallback(t *testing.T) {
+	getter := yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("v-" + key), nil
+	})
+	client := yolocache.NewGroup("replicas", 2<<10, getter)
+	server := yolocache.NewGroup("replicas", 2<<10, getter)
+	srv := httptest.NewServer(yolocache.NewHTTPPool("http://server"))
+	defer srv.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	pool := yolocache.NewHTTPPool("http://client", yolocache.WithReplicas(2))
+	pool.Set(dead.URL)
+	deadPeer, _ := pool.PickOwner("probe")
+	pool.AddPeers(srv.URL)
+	client.RegisterPeers(pool)
+
+	var key string
+	for i := 0; ; i++ {
+		key = fmt.Sprintf("key%d", i)
+		if p, _ := pool.PickPeer(key); p == deadPeer {
+			break
+		}
+	}
+	if n := len(pool.PickPeers(key)); n != 2 {
+		t.Fatalf("PickPeers returned %d peers, want 2", n)
+	}
+
+	view, err := client.Get(key)
+	if err != nil || view.String() != "v-"+key {
+		t.Fatalf("Get(%s) = %q, %v", key, view.String(), err)
+	}
+	if s := client.Stats(); s.PeerErrors != 1 || s.PeerLoads != 1 || s.LocalLoads != 0 {
+		t.Fatalf("client stats = %+v, want one peer error then a load from the replica", s)
+	}
+	if n := server.Stats().LocalLoads; n != 1 {
+		t.Fatalf("replica should have loaded the key once, got %d", n)
+	}
+}
+
+func TestVerifyRing(t *testing.T) {
+	var a, b *yolocache.HTTPPool
+	srvA := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { a.ServeHTTP(w, r) }))
+	defer srvA.Close()
+	srvB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { b.ServeHTTP(w, r) }))
+	defer srvB.Close()
+	a = yolocache.NewHTTPPool(srvA.URL)
+	b = yolocache.NewHTTPPool(srvB.URL)
+	// 添加顺序不同，哈希环仍然相同
+	a.Set(srvA.URL, srvB.URL)
+	b.Set(srvB.URL, srvA.URL)
+	if err := a.VerifyRing(context.Background()); err != nil {
+		t.Fatalf("identical rings: %v", err)
+	}
+
+	res, err := http.Get(srvA.URL + "/_yolocache/_ring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var ring struct {
+		Digest string         `json:"digest"`
+		Peers  map[string]int `json:"peers"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&ring); err != nil {
+		t.Fatal(err)
+	}
+	if ring.Digest != fmt.Sprintf("%016x", a.Digest()) || len(ring.Peers) != 2 {
+		t.Fatalf("ring endpoint = %+v", ring)
+	}
+
+	b.AddWeightedPeers(map[string]int{srvA.URL: 2})
+	if err := a.VerifyRing(context.Background()); err == nil {
+		t.Fatalf("expected a mismatch after changing a weight on one node")
+	}
+}
+
+// 两个节点的节点列表不一致，各自认为对方是所有者：收到转发请求的节点只在本地加载，不会再转发回去
+func TestForwardingLoop(t *testing.T) {
+	var poolA, poolB *yolocache.HTTPPool
+	srvA := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { poolA.ServeHTTP(w, r) }))
+	defer srvA.Close()
+	srvB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { poolB.ServeHTTP(w, r) }))
+	defer srvB.Close()
+
+	getter := yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("v-" + key), nil
+	})
+	a := yolocache.NewGroup("loop", 2<<10, getter)
+	b := yolocache.NewGroup("loop", 2<<10, getter) // 注册表中的是 b，两个服务端收到的请求都由 b 处理
+	poolA = yolocache.NewHTTPPool(srvA.URL)
+	poolA.Set(srvB.URL)
+	a.RegisterPeers(poolA)
+	poolB = yolocache.NewHTTPPool(srvB.URL)
+	poolB.Set(srvA.URL)
+	b.RegisterPeers(poolB)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	view, err := a.GetContext(ctx, "Tom")
+	if err != nil || view.String() != "v-Tom" {
+		t.Fatalf("Get = %q, %v", view.String(), err)
+	}
+	if s := b.Stats(); s.LocalLoads != 1 || s.PeerLoads != 0 || s.RingMismatches != 1 {
+		t.Fatalf("receiver stats = %+v, want one local load and one ring mismatch", s)
+	}
+}
+
+// 接收方拒绝转发次数超过1的请求，不会加载
+func TestRejectForwardedTwice(t *testing.T) {
+	loads := 0
+	server := yolocache.NewGroup("hops", 2<<10, yolocache.GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		return []byte(db[key]), nil
+	}))
+	srv := httptest.NewServer(yolocache.NewHTTPPool("http://server"))
+	defer srv.Close()
+	pool := yolocache.NewHTTPPool("http://client")
+	pool.Set(srv.URL)
+	peer, ok := pool.PickPeer("Tom")
+	if !ok {
+		t.Fatal("no peer picked")
+	}
+	ctx := context.Background()
+	if err := peer.Get(ctx, &pb.Request{Group: "hops", Key: "Tom", Hops: 2}, &pb.Response{}); err == nil {
+		t.Fatal("expected a request forwarded twice to be rejected")
+	}
+	err := peer.(yolocache.BatchGetter).GetBatch(ctx, &pb.BatchRequest{Group: "hops", Keys: []string{"Tom"}, Hops: 2}, &pb.BatchResponse{})
+	if err == nil {
+		t.Fatal("expected a batch forwarded twice to be rejected")
+	}
+	if loads != 0 {
+		t.Fatalf("rejected requests caused %d loads", loads)
+	}
+	// 转发一次的请求正常处理
+	res := &pb.Response{}
+	if err := peer.Get(ctx, &pb.Request{Group: "hops", Key: "Tom", Hops: 1}, res); err != nil || string(res.GetValue()) != db["Tom"] {
+		t.Fatalf("Get = %q, %v", res.GetValue(), err)
+	}
+	if s := server.Stats(); s.ServerRequests != 1 {
+		t.Fatalf("server requests = %d, want only the accepted one", s.ServerRequests)
+	}
+}
+
+func TestHealthEjection(t *testing.T) {
+	var down atomic.Bool
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer flaky.Close()
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }))
+	defer ok.Close()
+
+	pool := yolocache.NewHTTPPool("http://self", yolocache.WithHealthCheck(10*time.Millisecond, 2))
+	defer pool.Close()
+	pool.Set(flaky.URL)
+	flakyGetter, _ := pool.PickOwner("probe")
+	pool.AddPeers("http://self", ok.URL)
+
+	ejected := func() bool {
+		rec := httptest.NewRecorder()
+		pool.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_yolocache/_peers", nil))
+		var peers []struct {
+			Peer    string `json:"peer"`
+			Ejected bool   `json:"ejected"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&peers); err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range peers {
+			if p.Peer == flaky.URL {
+				return p.Ejected
+			}
+		}
+		t.Fatalf("%s missing from %+v", flaky.URL, peers)
+		return false
+	}
+	waitFor := func(want bool) {
+		deadline := time.Now().Add(2 * time.Second)
+		for ejected() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("ejected never became %v", want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	version, digest := pool.Version(), pool.Digest()
+	down.Store(true)
+	waitFor(true)
+	// 被摘除的节点不再被选中，但仍然是成员，哈希环的版本号和摘要也不变，其他节点不会因此报告节点列表不一致
+	if pool.Version() != version || pool.Digest() != digest {
+		t.Fatalf("ejection changed the ring: version %d -> %d, digest %016x -> %016x",
+			version, pool.Version(), digest, pool.Digest())
+	}
+	for i := 0; i < 100; i++ {
+		if g, ok := pool.PickOwner(fmt.Sprint("key", i)); ok && g == flakyGetter {
+			t.Fatalf("ejected peer picked for key%d", i)
+		}
+	}
+	if len(pool.Peers()) != 3 {
+		t.Fatalf("Peers() = %v, ejection must not change membership", pool.Peers())
+	}
+
+	down.Store(false)
+	waitFor(false)
+}
+
+// failingServer 在 fail 返回 true 时回复500，否则交给 HTTPPool 处理，返回收到的请求数
+func failingServer(t *testing.T, fail func(n int64) bool) (*httptest.Server, *atomic.Int64) {
+	var requests atomic.Int64
+	pool := yolocache.NewHTTPPool("http://server")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail(requests.Add(1)) {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		pool.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestPeerRetry(t *testing.T) {
+	getter := yolocache.GetterFunc(func(key string) ([]byte, error) {
+		if key == "missing" {
+			return nil, yolocache.ErrNotFound
+		}
+		return []byte("v-" + key), nil
+	})
+	client := yolocache.NewGroup("retry", 2<<10, getter)
+	yolocache.NewGroup("retry", 2<<10, getter)
+	// 前两次请求失败，第三次成功
+	srv, requests := failingServer(t, func(n int64) bool { return n <= 2 })
+	pool := yolocache.NewHTTPPool("http://client", yolocache.WithRetry(3, time.Millisecond))
+	pool.Set(srv.URL)
+	client.RegisterPeers(pool)
+
+	if view, err := client.Get("k"); err != nil || view.String() != "v-k" {
+		t.Fatalf("Get(k) = %q, %v", view.String(), err)
+	}
+	if s := client.Stats(); s.PeerLoads != 1 || s.PeerErrors != 0 || s.LocalLoads != 0 {
+		t.Fatalf("client stats = %+v, want a single successful peer load", s)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Fatalf("server got %d requests, want 3", n)
+	}
+
+	// 所有者明确回复的结果不重试
+	if _, err := client.Get("missing"); !errors.Is(err, yolocache.ErrNotFound) {
+		t.Fatalf("Get(missing) err = %v", err)
+	}
+	if n := requests.Load(); n != 4 {
+		t.Fatalf("server got %d requests, want 4: not found must not be retried", n)
+	}
+}
+
+func TestPeerTimeout(t *testing.T) {
+	getter := yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("v-" + key), nil
+	})
+	client := yolocache.NewGroup("timeout", 2<<10, getter)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+	pool := yolocache.NewHTTPPool("http://client",
+		yolocache.WithPeerTimeout(time.Minute),
+		yolocache.WithPeerTimeoutFor(slow.URL, 20*time.Millisecond))
+	pool.Set(slow.URL)
+	client.RegisterPeers(pool)
+
+	start := time.Now()
+	if view, err := client.Get("k"); err != nil || view.String() != "v-k" {
+		t.Fatalf("Get(k) = %q, %v", view.String(), err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatalf("Get took %v, the per-peer timeout was not applied", d)
+	}
+	if s := client.Stats(); s.PeerErrors != 1 || s.LocalLoads != 1 {
+		t.Fatalf("client stats = %+v, want a timed out peer then a local load", s)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	getter := yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("v-" + key), nil
+	})
+	client := yolocache.NewGroup("breaker", 2<<10, getter)
+	server := yolocache.NewGroup("breaker", 2<<10, getter)
+	var down atomic.Bool
+	down.Store(true)
+	srv, requests := failingServer(t, func(int64) bool { return down.Load() })
+	pool := yolocache.NewHTTPPool("http://client", yolocache.WithCircuitBreaker(2, 50*time.Millisecond))
+	pool.Set(srv.URL)
+	client.RegisterPeers(pool)
+
+	// 连续失败两次后熔断，之后的请求不再发给这个节点
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if view, err := client.Get(key); err != nil || view.String() != "v-"+key {
+			t.Fatalf("Get(%s) = %q, %v", key, view.String(), err)
+		}
+	}
+	if n := requests.Load(); n != 2 {
+		t.Fatalf("server got %d requests, want 2 before the breaker opened", n)
+	}
+	if _, ok := pool.PickPeer("e"); ok {
+		t.Fatalf("PickPeer chose a peer whose circuit is open")
+	}
+
+	// 冷却之后放行一个探测请求，成功后关闭
+	time.Sleep(60 * time.Millisecond)
+	down.Store(false)
+	if _, ok := pool.PickPeer("e"); !ok {
+		t.Fatalf("PickPeer should allow a probe after the cooldown")
+	}
+	for _, key := range []string{"e", "f"} {
+		if view, err := client.Get(key); err != nil || view.String() != "v-"+key {
+			t.Fatalf("Get(%s) = %q, %v", key, view.String(), err)
+		}
+	}
+	if n := server.Stats().LocalLoads; n != 2 {
+		t.Fatalf("server loaded %d keys after recovery, want 2", n)
+	}
+}
+
+func TestHedge(t *testing.T) {
+	client := yolocache.NewGroup("hedge", 2<<10, yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("local-" + key), nil
+	}), yolocache.WithHedge(20*time.Millisecond))
+	yolocache.NewGroup("hedge", 2<<10, yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("peer-" + key), nil
+	}))
+	cancelled := make(chan struct{}, 1)
+	server := yolocache.NewHTTPPool("http://server")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 对 slow 的请求一直拖到被取消
+		if strings.HasSuffix(r.URL.Path, "/slow") {
+			<-r.Context().Done()
+			cancelled <- struct{}{}
+			return
+		}
+		server.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	pool := yolocache.NewHTTPPool("http://client")
+	pool.Set(srv.URL)
+	client.RegisterPeers(pool)
+
+	// 节点及时回复时不对冲
+	if view, err := client.Get("fast"); err != nil || view.String() != "peer-fast" {
+		t.Fatalf("Get(fast) = %q, %v", view.String(), err)
+	}
+	if s := client.Stats(); s.Hedges != 0 {
+		t.Fatalf("stats = %+v, a fast peer must not be hedged", s)
+	}
+
+	// 节点太慢时在本地加载，先得到的结果获胜，慢的请求被取消
+	start := time.Now()
+	if view, err := client.Get("slow"); err != nil || view.String() != "local-slow" {
+		t.Fatalf("Get(slow) = %q, %v", view.String(), err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatalf("hedged Get took %v", d)
+	}
+	if s := client.Stats(); s.Hedges != 1 || s.HedgeWins != 1 || s.PeerErrors != 0 {
+		t.Fatalf("stats = %+v, want one winning hedge and no peer errors", s)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatalf("the losing peer request was not cancelled")
+	}
+}
+
+// countingTransport 统计经过它的请求数
+type countingTransport struct{ n atomic.Int64 }
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.n.Add(1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestWithTransport(t *testing.T) {
+	getter := yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("v-" + key), nil
+	})
+	client := yolocache.NewGroup("transport", 2<<10, getter)
+	yolocache.NewGroup("transport", 2<<10, getter)
+	var server *yolocache.HTTPPool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { server.ServeHTTP(w, r) }))
+	defer srv.Close()
+	server = yolocache.NewHTTPPool(srv.URL)
+	server.Set(srv.URL, "http://client")
+
+	rt := &countingTransport{}
+	pool := yolocache.NewHTTPPool("http://client", yolocache.WithTransport(rt))
+	pool.Set(srv.URL, "http://client")
+	client.RegisterPeers(pool)
+	for i := 0; i < 20; i++ {
+		if _, err := client.Get(fmt.Sprint("key", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	peerLoads := client.Stats().PeerLoads
+	if peerLoads == 0 || rt.n.Load() != peerLoads {
+		t.Fatalf("transport saw %d requests for %d peer loads", rt.n.Load(), peerLoads)
+	}
+	if err := pool.VerifyRing(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if rt.n.Load() != peerLoads+1 {
+		t.Fatalf("VerifyRing did not use the configured transport")
+	}
+}
+
+// 节点之间的请求复用连接，包括返回了错误的请求
+func TestPeerConnectionReuse(t *testing.T) {
+	yolocache.NewGroup("reuse", 2<<10, yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("v-" + key), nil
+	}))
+	var conns atomic.Int64
+	srv := httptest.NewUnstartedServer(yolocache.NewHTTPPool("http://server"))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+	pool := yolocache.NewHTTPPool("http://client")
+	pool.Set(srv.URL)
+	peer, _ := pool.PickOwner("probe")
+
+	// 几轮并发请求，每轮最多16个连接，之后的轮次复用空闲的连接
+	const rounds, concurrency = 5, 16
+	for round := 0; round < rounds; round++ {
+		var wg sync.WaitGroup
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				// 不存在的Group返回纯文本的404
+				group := "reuse"
+				if i%2 == 1 {
+					group = "nosuchgroup"
+				}
+				err := peer.Get(context.Background(), &pb.Request{Group: group, Key: fmt.Sprint("key", i)}, &pb.Response{})
+				if (err != nil) != (group == "nosuchgroup") {
+					t.Errorf("Get(%s) err = %v", group, err)
+				}
+			}(i)
+		}
+		wg.Wait()
+	}
+	if n := conns.Load(); n > concurrency {
+		t.Fatalf("opened %d connections for %d rounds of %d concurrent requests", n, rounds, concurrency)
+	}
+}
+
+// 两个客户端向所有者请求同一个 key，先发起加载的客户端中途取消，不应让另一个也失败
+func TestSharedLoadCallerCancel(t *testing.T) {
+	release := make(chan struct{})
+	var loads atomic.Int64
+	yolocache.NewGroup("sharedcancel", 2<<10, yolocache.GetterCtxFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			loads.Add(1)
+			select {
+			case <-release:
+				return []byte(db[key]), nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}))
+	srv := httptest.NewServer(yolocache.NewHTTPPool("http://server"))
+	t.Cleanup(srv.Close)
+
+	get := func(ctx context.Context) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/_yolocache/sharedcancel/Tom", nil)
+		if err != nil {
+			return "", err
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			return "", err
+		}
+		out := &pb.Response{}
+		if err := proto.Unmarshal(body, out); err != nil {
+			return "", err
+		}
+		if res.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("status %d: %s", res.StatusCode, out.GetError())
+		}
+		return string(out.GetValue()), nil
+	}
+
+	// 第一个客户端发起加载
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, err := get(ctx)
+		leader <- err
+	}()
+	for loads.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// 第二个客户端复用同一次加载，随后第一个客户端放弃
+	patient := make(chan string, 1)
+	go func() {
+		v, err := get(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		patient <- v
+	}()
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+	if err := <-leader; err == nil {
+		t.Fatal("expected the cancelled client to fail")
+	}
+	time.Sleep(30 * time.Millisecond) // 给服务端留出感知连接断开的时间
+	close(release)
+	if v := <-patient; v != db["Tom"] {
+		t.Fatalf("patient client got %q", v)
+	}
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("loads = %d, want 1", n)
+	}
+}
@@ -0,0 +1,137 @@
+package test
+
+import (
+	"YoloCache/yolocache"
+	pb "YoloCache/yolocache/yolocachepb"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+)
+
+const streamContentType = "application/x-yolocache-stream"
+
+// bigValue 返回一个确定的、不可压缩的大值
+func bigValue(n int) []byte {
+	b := make([]byte, n)
+	rand.New(rand.NewSource(int64(n))).Read(b)
+	return b
+}
+
+func TestStreamLargeValue(t *testing.T) {
+	big := bigValue(3<<20 + 123)
+	getter := yolocache.GetterFunc(func(key string) ([]byte, error) {
+		if key == "big" {
+			return big, nil
+		}
+		return []byte("small"), nil
+	})
+	client := yolocache.NewGroup("stream", 2<<10, getter)
+	server := yolocache.NewGroup("stream", 8<<20, getter)
+	var streamed atomic.Int64
+	pool := yolocache.NewHTTPPool("http://server")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pool.ServeHTTP(w, r)
+		if w.Header().Get("Content-Type") == streamContentType {
+			streamed.Add(1)
+		}
+	}))
+	defer srv.Close()
+	peers := yolocache.NewHTTPPool("http://client")
+	peers.Set(srv.URL)
+	client.RegisterPeers(peers)
+
+	r, err := client.GetReader(context.Background(), "big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(got, big) {
+		t.Fatalf("streamed value differs: %d bytes, %v", len(got), err)
+	}
+	if v, err := client.Get("small"); err != nil || v.String() != "small" {
+		t.Fatalf("Get(small) = %q, %v", v.String(), err)
+	}
+	if n := streamed.Load(); n != 1 {
+		t.Fatalf("%d responses were streamed, want only the large one", n)
+	}
+	if n := server.Stats().LocalLoads; n != 2 {
+		t.Fatalf("server loaded %d values, want 2", n)
+	}
+
+	// 不支持流式传输的旧版本节点仍然收到 pb.Response
+	res, err := http.Get(srv.URL + "/_yolocache/stream/big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	out := &pb.Response{}
+	if err := proto.Unmarshal(body, out); err != nil || !bytes.Equal(out.GetValue(), big) {
+		t.Fatalf("legacy response: %d bytes, %v", len(out.GetValue()), err)
+	}
+}
+
+// 流在结束标记之前断开时，调用方把它当作节点的失败，回退到本地加载
+func TestStreamTruncated(t *testing.T) {
+	client := yolocache.NewGroup("truncated", 2<<10, yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("local"), nil
+	}))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", streamContentType)
+		var hdr [12]byte
+		binary.BigEndian.PutUint64(hdr[:8], 100)
+		binary.BigEndian.PutUint32(hdr[8:], 100)
+		w.Write(hdr[:])
+		w.Write(make([]byte, 10))
+	}))
+	defer srv.Close()
+	peers := yolocache.NewHTTPPool("http://client")
+	peers.Set(srv.URL)
+	client.RegisterPeers(peers)
+
+	if v, err := client.Get("k"); err != nil || v.String() != "local" {
+		t.Fatalf("Get = %q, %v", v.String(), err)
+	}
+	if s := client.Stats(); s.PeerErrors != 1 || s.LocalLoads != 1 {
+		t.Fatalf("stats = %+v, want a peer error then a local load", s)
+	}
+}
+
+// 流式传输的总长度超过 WithMaxValueSize 时不读入这个值，回退到本地加载，也不重试
+func TestStreamMaxValueSize(t *testing.T) {
+	big := bigValue(256 << 10)
+	client := yolocache.NewGroup("stream-limit", 2<<10, yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return []byte("local"), nil
+	}))
+	yolocache.NewGroup("stream-limit", 2<<10, yolocache.GetterFunc(func(key string) ([]byte, error) {
+		return big, nil
+	}))
+	var requests, streamed atomic.Int64
+	pool := yolocache.NewHTTPPool("http://server", yolocache.WithStreamThreshold(1))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		pool.ServeHTTP(w, r)
+		if w.Header().Get("Content-Type") == streamContentType {
+			streamed.Add(1)
+		}
+	}))
+	defer srv.Close()
+	peers := yolocache.NewHTTPPool("http://client", yolocache.WithMaxValueSize(64<<10), yolocache.WithRetry(3, 0))
+	peers.Set(srv.URL)
+	client.RegisterPeers(peers)
+
+	if v, err := client.Get("big"); err != nil || v.String() != "local" {
+		t.Fatalf("Get = %d bytes, %v, want the oversized stream to be rejected", v.Len(), err)
+	}
+	if r, s := requests.Load(), streamed.Load(); r != 1 || s != 1 {
+		t.Fatalf("server got %d requests (%d streamed), want one streamed request", r, s)
+	}
+}
@@ -0,0 +1,69 @@
+package yolocache
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+/*
+***********************节点间通信的HTTP客户端*********************************
+http.DefaultClient 背后的 http.DefaultTransport 对每个主机最多只保留2个空闲连接，
+并发稍高时多出来的连接用完就被关闭，下一次又要重新建立TCP连接。
+节点之间的请求数量多、目标主机固定，所以默认使用一个为此调整过的连接池，所有 HTTPPool 共享，
+也可以通过 WithHTTPClient 或 WithTransport 换成自己的配置，比如自定义的 Dialer、TLS 或代理。
+*/
+
+const (
+	defaultMaxIdleConns        = 512
+	defaultMaxIdleConnsPerHost = 64 // 每个节点保留的空闲连接数，决定了高并发下能复用多少连接
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultDialTimeout         = 5 * time.Second
+	defaultKeepAlive           = 30 * time.Second
+)
+
+// defaultTransport 是所有 HTTPPool 默认共享的连接池
+var defaultTransport = newDefaultTransport()
+
+func newDefaultTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   defaultDialTimeout,
+		KeepAlive: defaultKeepAlive,
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true, // 节点地址是 https:// 时使用 HTTP/2，多个请求复用一个连接
+		MaxIdleConns:          defaultMaxIdleConns,
+		MaxIdleConnsPerHost:   defaultMaxIdleConnsPerHost,
+		IdleConnTimeout:       defaultIdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// WithHTTPClient 使用 c 请求其他节点（Get、Set、Remove、健康检查和核对哈希环）。
+// 请求的超时由 WithPeerTimeout 和调用方的ctx控制，c.Timeout 会在它们之外再限制一次
+func WithHTTPClient(c *http.Client) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		if c != nil {
+			p.client = c
+		}
+	}
+}
+
+// WithTransport 使用 rt 作为请求其他节点的 Transport，如自己配置的 *http.Transport 或 http2.Transport
+func WithTransport(rt http.RoundTripper) HTTPPoolOption {
+	return func(p *HTTPPool) {
+		if rt != nil {
+			p.client = &http.Client{Transport: rt}
+		}
+	}
+}
+
+// closeBody 读完剩余的 body 再关闭，这样连接才能回到连接池被复用
+func closeBody(res *http.Response) {
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+}
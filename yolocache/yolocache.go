@@ -3,9 +3,13 @@ package yolocache
 import (
 	"YoloCache/yolocache/singleflight"
 	pb "YoloCache/yolocache/yolocachepb"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
 	"sync"
+	"time"
 )
 
 /*
@@ -34,11 +38,79 @@ type Group struct {
 	mainCache cache               // 第三个属性是 mainCache cache，即一开始实现的并发缓存。
 	peers     PeerPicker          // 将用于获取远程节点
 	loader    *singleflight.Group // 管理请求的数据结构，这里为什么要想到把singleflight里的group加到Group中？ 可以想到， 他们应该在一起初始化。所以下一步就是更新初始化函数
+	ttl       time.Duration       // 缓存值默认的存活时间，0 表示永不过期
+
+	// localLoader 对其他节点转发过来的请求去重。这些请求只在本地加载，不能与可能转发出去的 loader 共用，
+	// 否则两个节点互相转发同一个key时，会各自等待对方的结果而卡住
+	localLoader *singleflight.Group
+
+	// hotCache 缓存一部分从其他节点获取到的热点值，避免热点key的每次请求都打到同一个所有者节点上。
+	// 它比mainCache小得多，容量是cacheBytes的hotCacheRatio倍，只有按hotAdmitRate的概率被选中的值才会放进来，
+	// 这样越热的key越可能被缓存，而偶尔访问一次的key基本不会挤占空间。
+	// 其他节点上的值被修改后本节点无法得知，hotCache中的副本会一直旧到过期为止，所以它默认关闭，开启时副本也只保留hotTTL
+	hotCache      cache
+	hotCacheRatio float64       // hotCache的容量占cacheBytes的比例，0表示关闭hotCache（默认）
+	hotAdmitRate  float64       // 从其他节点获取到的值被放入hotCache的概率
+	hotTTL        time.Duration // hotCache中副本的存活时间，即可能读到旧值的最长时间
+
+	// negCache 缓存数据源中不存在的key（Getter返回了ErrNotFound），在negTTL内再次请求同一个key时直接返回ErrNotFound，
+	// 不再打扰数据源。它有独立的容量，避免大量不存在的key把正常的值挤出mainCache
+	negCache cache
+	negTTL   time.Duration // "不存在"结果的存活时间，0表示关闭负缓存
+
+	hedgeDelay time.Duration // 请求节点超过这个时间没有回复时发起对冲请求，0表示不对冲
+
+	stats groupStats // 运行统计，通过 Stats() 获取快照
+}
+
+const defaultHotCacheTTL = 10 * time.Second
+
+// GroupOption 用于在 NewGroup 时配置 Group 的可选项
+type GroupOption func(*Group)
+
+// WithTTL 设置 Group 中缓存值默认的存活时间，Getter 没有单独指定 TTL 时使用它，0 表示永不过期
+func WithTTL(ttl time.Duration) GroupOption {
+	return func(g *Group) {
+		g.ttl = ttl
+	}
+}
+
+// WithHotCache 开启hotCache，设置它的容量比例、准入概率和副本的存活时间，ratio为0时关闭hotCache。
+// 所有者上的值被修改后，本节点在ttl内仍可能读到旧值，ttl不大于0时使用默认的10秒；Group的ttl更短时以Group的为准
+func WithHotCache(ratio, admitRate float64, ttl time.Duration) GroupOption {
+	return func(g *Group) {
+		g.hotCacheRatio = ratio
+		g.hotAdmitRate = admitRate
+		if ttl <= 0 {
+			ttl = defaultHotCacheTTL
+		}
+		g.hotTTL = ttl
+	}
+}
+
+// WithHedge 开启对冲请求：从其他节点获取值超过 delay 还没有回复时，向下一个副本（没有副本时在本地）再发起一次同样的请求，
+// 先得到的结果获胜，另一个请求被取消。这样偶尔变慢的节点不会拖慢所有请求，代价是最多多出一次请求。
+// delay 一般取节点请求耗时的 p95 左右，0 表示关闭（默认）
+func WithHedge(delay time.Duration) GroupOption {
+	return func(g *Group) {
+		g.hedgeDelay = delay
+	}
+}
+
+// WithNegativeCache 开启负缓存：数据源中不存在的key在ttl内会被记住，cacheBytes是负缓存独立的容量，0表示不限制
+func WithNegativeCache(ttl time.Duration, cacheBytes int64) GroupOption {
+	return func(g *Group) {
+		g.negTTL = ttl
+		g.negCache.cacheBytes = cacheBytes
+	}
 }
 
 var (
 	mu     sync.RWMutex              // 全局的锁
 	groups = make(map[string]*Group) // 全局的一个groups
+
+	sweepOnce     sync.Once     // 保证后台清理协程只启动一次
+	sweepInterval = time.Minute // 后台清理过期记录的间隔
 )
 
 // RegisterPeers RegisterPeers方法，将 实现了 PeerPicker 接口的 HTTPPool 注入到 Group 中。
@@ -50,7 +122,7 @@ func (g *Group) RegisterPeers(peers PeerPicker) {
 }
 
 // NewGroup 构建NewGroup， 实例化Group
-func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
+func NewGroup(name string, cacheBytes int64, getter Getter, opts ...GroupOption) *Group {
 	if getter == nil {
 		panic("nil Getter")
 	}
@@ -61,11 +133,45 @@ func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
 		getter:    getter,
 		mainCache: cache{cacheBytes: cacheBytes},
 		loader:    &singleflight.Group{},
+
+		localLoader: &singleflight.Group{},
 	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	g.hotCache.cacheBytes = int64(float64(cacheBytes) * g.hotCacheRatio)
 	groups[name] = g
+	sweepOnce.Do(func() {
+		go sweepExpired()
+	})
 	return g
 }
 
+// sweepExpired 定期清理所有Group中已过期但一直没有被访问到的记录。
+// 所有Group共用这一个协程，避免每创建一个Group就多一个常驻协程
+func sweepExpired() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		for _, g := range allGroups() {
+			g.mainCache.removeExpired()
+			g.hotCache.removeExpired()
+			g.negCache.removeExpired()
+		}
+	}
+}
+
+// allGroups 返回当前注册的所有Group
+func allGroups() []*Group {
+	mu.RLock()
+	defer mu.RUnlock()
+	gs := make([]*Group, 0, len(groups))
+	for _, g := range groups {
+		gs = append(gs, g)
+	}
+	return gs
+}
+
 func GetGroup(name string) *Group {
 	// 这里用的是只读锁,因为不涉及任何冲突变量的写操作。
 	mu.RLock()
@@ -82,14 +188,19 @@ func GetGroup(name string) *Group {
 */
 
 func (g *Group) Get(key string) (ByteView, error) {
+	return g.GetContext(context.Background(), key)
+}
+
+// GetContext 与 Get 相同，但 ctx 会贯穿整个加载流程：singleflight 的等待、远程节点的 HTTP 请求以及用户的回调函数，
+// 调用方可以借此设置超时或主动取消，避免被慢数据源或者卡住的远程节点一直阻塞。
+func (g *Group) GetContext(ctx context.Context, key string) (ByteView, error) {
 	// 如果key为空，返回以零值初始化的ByteView实例，和错误
 	if key == "" {
 		return ByteView{}, fmt.Errorf("key is required")
 	}
-	// 从 mainCache 中查找缓存，如果存在则返回缓存值。
-	if v, ok := g.mainCache.get(key); ok {
-		log.Println("[YoloCache] hit")
-		return v, nil
+	g.stats.gets.Add(1)
+	if v, err, ok := g.lookupCache(key); ok {
+		return v, err
 	}
 	/*
 		缓存不存在，尝试去其他节点寻找缓存。 调用 load 方法，
@@ -97,27 +208,65 @@ func (g *Group) Get(key string) (ByteView, error) {
 		getLocally 调用用户回调函数 g.getter.Get() 获取源数据，
 		并且将源数据添加到缓存 mainCache 中（通过 populateCache 方法）
 	*/
-	return g.load(key)
+	return g.load(ctx, key)
+}
+
+// lookupCache 依次查找本节点的各个缓存，ok 为 true 表示命中（包括负缓存命中，此时 err 为 ErrNotFound）
+func (g *Group) lookupCache(key string) (value ByteView, err error, ok bool) {
+	// 从 mainCache 中查找缓存，如果存在则返回缓存值。
+	if v, ok := g.mainCache.get(key); ok {
+		log.Println("[YoloCache] hit")
+		g.stats.cacheHits.Add(1)
+		return v, nil, true
+	}
+	// 再从 hotCache 中查找，这里存的是属于其他节点、但近期被频繁访问的值
+	if v, ok := g.hotCache.get(key); ok {
+		log.Println("[YoloCache] hot hit")
+		g.stats.cacheHits.Add(1)
+		return v, nil, true
+	}
+	// 最后查找负缓存，命中说明这个key不久前刚被确认过不存在
+	if _, ok := g.negCache.get(key); ok {
+		g.stats.negativeHits.Add(1)
+		return ByteView{}, fmt.Errorf("%s: %w", key, ErrNotFound), true
+	}
+	return ByteView{}, nil, false
+}
+
+// getForPeer 处理其他节点转发过来的 Get 请求：查找缓存，未命中时只在本地加载，不再转发。
+// 转发方已经认为本节点是所有者（或者所有者的副本），即使两个节点的节点列表不一致，请求也最多转发一次
+func (g *Group) getForPeer(ctx context.Context, key string) (ByteView, error) {
+	if key == "" {
+		return ByteView{}, fmt.Errorf("key is required")
+	}
+	g.stats.gets.Add(1)
+	if v, err, ok := g.lookupCache(key); ok {
+		return v, err
+	}
+	g.stats.loads.Add(1)
+	view, err, shared := g.localLoader.DoContext(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return g.getLocally(ctx, key)
+	})
+	if shared {
+		g.stats.loadsDeduped.Add(1)
+	}
+	if err != nil {
+		return ByteView{}, err
+	}
+	return view.(ByteView), nil
 }
 
 // 当在本节点没有找到时，调用load尝试从其他节点获取
 // 设计时预留：分布式场景下，load 会先从远程节点获取 getFromPeer，失败了再回退到 getLocally
-func (g *Group) load(key string) (value ByteView, err error) {
+func (g *Group) load(ctx context.Context, key string) (value ByteView, err error) {
+	g.stats.loads.Add(1)
 	// 使用g.loader.Do包裹原来的代码，这样确保了在并发场景下针对相同的key,load过程只会调用一次 day6
-	view, err := g.loader.Do(key, func() (interface{}, error) {
-		// 如果有其他节点存在
-		if g.peers != nil {
-			// 如果是分布式节点，从其他节点获取， 这里p返回的peer是目标节点的
-			if peer, ok := g.peers.PickPeer(key); ok {
-				// 再用这个baseurl传入getFromPeer函数中，去获取这个key的value
-				if value, err = g.getFromPeer(peer, key); err == nil {
-					return value, nil // 从其他节点获取成功，返回
-				}
-				log.Println("[YoloCache] Failed to get from peer", err)
-			}
-		}
-		return g.getLocally(key)
+	view, err, shared := g.loader.DoContext(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return g.fetch(ctx, key)
 	})
+	if shared {
+		g.stats.loadsDeduped.Add(1)
+	}
 	// day6
 	if err == nil {
 		return view.(ByteView), nil
@@ -125,7 +274,113 @@ func (g *Group) load(key string) (value ByteView, err error) {
 	return
 }
 
-func (g *Group) getFromPeer(peer PeerGetter, key string) (ByteView, error) {
+// fetch 是 load 中由 g.loader 去重的部分：先问key的所有者（以及副本），都失败了再回退到本地加载
+func (g *Group) fetch(ctx context.Context, key string) (ByteView, error) {
+	// 如果是分布式节点，从其他节点获取，这里返回的是key的所有者（以及副本）节点
+	peers := g.pickPeers(key)
+	if g.hedgeDelay > 0 && len(peers) > 0 {
+		return g.hedgedLoad(ctx, key, peers)
+	}
+	return g.loadFromPeers(ctx, key, peers)
+}
+
+// loadFromPeers 按顺序尝试 peers，前一个节点失败时再问下一个，全部失败才回退到本地加载
+func (g *Group) loadFromPeers(ctx context.Context, key string, peers []PeerGetter) (ByteView, error) {
+	for _, peer := range peers {
+		value, err, final := g.tryPeer(ctx, peer, key)
+		if final {
+			return value, err
+		}
+		// 调用方已经放弃了，就没必要再回退到本地加载了
+		if ctx.Err() != nil {
+			return ByteView{}, ctx.Err()
+		}
+		log.Println("[YoloCache] Failed to get from peer", err)
+	}
+	return g.getLocally(ctx, key)
+}
+
+// hedgedLoad 先请求第一个节点，hedgeDelay 内没有回复时，同时用 loadFromPeers 向其余的副本请求（没有副本时就是本地加载），
+// 两边先得到确定结果的获胜，另一边被取消。第一个节点在 hedgeDelay 之前就失败了，就和不对冲时一样直接问下一个
+func (g *Group) hedgedLoad(ctx context.Context, key string, peers []PeerGetter) (ByteView, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // 返回时取消输掉的一边
+	type result struct {
+		value ByteView
+		err   error
+		final bool
+	}
+	primary := make(chan result, 1)
+	go func() {
+		value, err, final := g.tryPeer(ctx, peers[0], key)
+		primary <- result{value, err, final}
+	}()
+	timer := time.NewTimer(g.hedgeDelay)
+	defer timer.Stop()
+
+	var hedge chan result
+	startHedge := func() {
+		hedge = make(chan result, 1)
+		go func() {
+			value, err := g.loadFromPeers(ctx, key, peers[1:])
+			// 本地加载的结果总是确定的
+			hedge <- result{value, err, true}
+		}()
+	}
+	for {
+		select {
+		case r := <-primary:
+			if r.final {
+				return r.value, r.err
+			}
+			if ctx.Err() != nil {
+				return ByteView{}, ctx.Err()
+			}
+			log.Println("[YoloCache] Failed to get from peer", r.err)
+			primary = nil // 第一个节点失败了，只等对冲的一边
+			if hedge == nil {
+				startHedge()
+			}
+		case <-timer.C:
+			if hedge == nil {
+				g.stats.hedges.Add(1)
+				startHedge()
+			}
+		case r := <-hedge:
+			if primary != nil {
+				g.stats.hedgeWins.Add(1)
+			}
+			return r.value, r.err
+		case <-ctx.Done():
+			return ByteView{}, ctx.Err()
+		}
+	}
+}
+
+// tryPeer 从一个远程节点获取key，final 表示结果是确定的，不需要再问其他节点或回退到本地
+func (g *Group) tryPeer(ctx context.Context, peer PeerGetter, key string) (value ByteView, err error, final bool) {
+	// 再用这个baseurl传入getFromPeer函数中，去获取这个key的value
+	if value, err = g.getFromPeer(ctx, peer, key); err == nil {
+		g.stats.peerLoads.Add(1)
+		g.maybePopulateHotCache(key, value)
+		return value, nil, true // 从其他节点获取成功，返回
+	}
+	// 所有者明确回复了不存在或回调函数失败，说明它已经问过数据源了，
+	// 这时回退到本地只会让数据源多承受一次请求，直接把错误返回给用户
+	if isAuthoritative(err) {
+		g.stats.peerLoads.Add(1)
+		// 所有者确认不存在，本节点也记住这个结果，各节点对这个key的判断保持一致
+		g.populateNegativeCache(key, err)
+		return ByteView{}, err, true
+	}
+	// 被取消的请求（调用方放弃了，或者对冲时输掉了）不算节点的错误
+	if ctx.Err() == nil {
+		g.stats.peerErrors.Add(1)
+	}
+	return ByteView{}, err, false
+}
+
+func (g *Group) getFromPeer(ctx context.Context, peer PeerGetter, key string) (ByteView, error) {
 	// RPC前的版本
 	//// 调用peer的Get方法，向其他节点发起请求，查询value
 	//bytes, err := peer.Get(g.name, key)
@@ -136,9 +391,10 @@ func (g *Group) getFromPeer(peer PeerGetter, key string) (ByteView, error) {
 	req := &pb.Request{
 		Group: g.name,
 		Key:   key,
+		Hops:  1, // 标记这是节点之间转发的请求，接收方总是只在本地加载，所以不会再转发
 	}
 	res := &pb.Response{}
-	err := peer.Get(req, res)
+	err := peer.Get(ctx, req, res)
 	if err != nil {
 		return ByteView{}, err
 	}
@@ -146,23 +402,156 @@ func (g *Group) getFromPeer(peer PeerGetter, key string) (ByteView, error) {
 
 }
 
+// Set 主动写入key对应的缓存值，写请求会被路由到key的所有者节点，
+// 这样写入方无需等待LRU淘汰，就能让所有节点读到新值
+func (g *Group) Set(key string, value []byte) error {
+	return g.SetContext(context.Background(), key, value)
+}
+
+// SetContext 与 Set 相同，ctx 会传递到与所有者节点的通信上
+func (g *Group) SetContext(ctx context.Context, key string, value []byte) error {
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+	// key属于其他节点，转发给所有者; 转发失败时直接返回错误，不能写到本地，否则其他节点永远读不到
+	if g.peers != nil {
+		if peer, ok := g.pickOwner(key); ok {
+			// 本节点hotCache和negCache中可能存着旧的结果，先删掉，保证自己能读到自己的写入
+			g.forget(key)
+			req := &pb.SetRequest{Group: g.name, Key: key, Value: value}
+			return peer.Set(ctx, req, &pb.Response{})
+		}
+	}
+	g.setLocally(key, value)
+	return nil
+}
+
+// Remove 删除key对应的缓存值，同样会被路由到key的所有者节点，用于让缓存值立即失效
+func (g *Group) Remove(key string) error {
+	return g.RemoveContext(context.Background(), key)
+}
+
+// RemoveContext 与 Remove 相同，ctx 会传递到与所有者节点的通信上
+func (g *Group) RemoveContext(ctx context.Context, key string) error {
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+	if g.peers != nil {
+		if peer, ok := g.pickOwner(key); ok {
+			g.forget(key)
+			req := &pb.Request{Group: g.name, Key: key}
+			return peer.Remove(ctx, req, &pb.Response{})
+		}
+	}
+	g.removeLocally(key)
+	return nil
+}
+
+// pickPeers 为读请求选择要尝试的远程节点，PeerPicker 实现了 ReplicaPicker 时返回所有副本，
+// 否则最多只有 PickPeer 选出的一个节点。返回空时由本节点加载
+func (g *Group) pickPeers(key string) []PeerGetter {
+	if g.peers == nil {
+		return nil
+	}
+	if rp, ok := g.peers.(ReplicaPicker); ok {
+		return rp.PickPeers(key)
+	}
+	if peer, ok := g.peers.PickPeer(key); ok {
+		return []PeerGetter{peer}
+	}
+	return nil
+}
+
+// pickOwner 为写请求选择key的所有者节点，PeerPicker 实现了 OwnerPicker 时优先使用它
+func (g *Group) pickOwner(key string) (PeerGetter, bool) {
+	if op, ok := g.peers.(OwnerPicker); ok {
+		return op.PickOwner(key)
+	}
+	return g.peers.PickPeer(key)
+}
+
+// setLocally 将值写入本节点的缓存，value会被拷贝一份，防止调用方之后修改
+func (g *Group) setLocally(key string, value []byte) {
+	g.negCache.remove(key)
+	g.populateCache(key, ByteView{b: cloneBytes(value)}, 0)
+}
+
+// removeLocally 删除本节点缓存中的值
+func (g *Group) removeLocally(key string) {
+	g.mainCache.remove(key)
+	g.forget(key)
+}
+
+// forget 删除本节点上关于key的非权威结果：hotCache中的副本和负缓存
+func (g *Group) forget(key string) {
+	g.hotCache.remove(key)
+	g.negCache.remove(key)
+}
+
 // 从本地没找到，先尝试去从其他节点找，如果其他节点也没找到的话，那就再返回本地来，去调用的回调函数，获取数据源中的数据，再添加到缓存中并返回
-func (g *Group) getLocally(key string) (ByteView, error) {
+func (g *Group) getLocally(ctx context.Context, key string) (ByteView, error) {
 	// 调用用户回调函数g.getter.Get() 获取源数据
-	bytes, err := g.getter.Get(key) // Get方法返回f(key)， 这里也就是把key传到用户提供的匿名函数中，调用获取返回值
+	// 如果用户的Getter同时实现了GetterCtx，就把ctx交给它，让数据源也能感知超时和取消
+	// 如果实现了GetterTTL，还可以为这个值单独指定存活时间
+	var bytes []byte
+	var ttl time.Duration
+	var err error
+	if gt, ok := g.getter.(GetterTTL); ok {
+		bytes, ttl, err = gt.GetWithTTL(ctx, key)
+	} else if gc, ok := g.getter.(GetterCtx); ok {
+		bytes, err = gc.GetContext(ctx, key)
+	} else {
+		bytes, err = g.getter.Get(key) // Get方法返回f(key)， 这里也就是把key传到用户提供的匿名函数中，调用获取返回值
+	}
 	// 获取失败
 	if err != nil {
+		g.stats.localLoadErrs.Add(1)
+		g.populateNegativeCache(key, err)
 		return ByteView{}, err
 	}
+	g.stats.localLoads.Add(1)
 	// 获取成功，添加到缓存mainCache中
 	value := ByteView{b: cloneBytes(bytes)}
-	g.populateCache(key, value)
+	g.populateCache(key, value, ttl)
 	return value, nil
 }
 
-func (g *Group) populateCache(key string, value ByteView) {
+// populateCache ttl 为 0 时使用 Group 默认的存活时间
+func (g *Group) populateCache(key string, value ByteView, ttl time.Duration) {
 	// 添加到mainCache中
-	g.mainCache.add(key, value)
+	g.mainCache.add(key, value, g.expireAt(ttl))
+}
+
+// populateNegativeCache 如果err表示key不存在，就把它记入负缓存，其他错误（如数据源超时）不缓存
+func (g *Group) populateNegativeCache(key string, err error) {
+	if g.negTTL <= 0 || !errors.Is(err, ErrNotFound) {
+		return
+	}
+	g.negCache.add(key, ByteView{}, time.Now().Add(g.negTTL))
+}
+
+// maybePopulateHotCache 按准入概率决定是否把从其他节点获取到的值放入hotCache。
+// 其他节点上的值被修改后，本节点无法得知，所以hotCache里的值最多只保留hotTTL
+func (g *Group) maybePopulateHotCache(key string, value ByteView) {
+	if g.hotCache.cacheBytes <= 0 || rand.Float64() >= g.hotAdmitRate {
+		return
+	}
+	ttl := g.hotTTL
+	if g.ttl > 0 && g.ttl < ttl {
+		ttl = g.ttl
+	}
+	g.hotCache.add(key, value, time.Now().Add(ttl))
+}
+
+// expireAt 根据ttl计算过期时刻，ttl为0时使用Group默认的ttl，最终ttl不大于0则返回零值，表示永不过期
+func (g *Group) expireAt(ttl time.Duration) time.Time {
+	if ttl == 0 {
+		ttl = g.ttl
+	}
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
 }
 
 // 回调Getter
@@ -193,3 +582,52 @@ func (f GetterFunc) Get(key string) ([]byte, error) {
 	// 所以这里的f，就是匿名函数， 传入key，即以key为参数，调用匿名函数
 	return f(key)
 }
+
+// GetterCtx 是 Getter 的带 ctx 版本，实现了该接口的 Getter 在加载时会收到调用方的 ctx，
+// 可以把超时和取消继续传递给数据库等数据源。
+type GetterCtx interface {
+	GetContext(ctx context.Context, key string) ([]byte, error)
+}
+
+// GetterCtxFunc 是 GetterCtx 的接口型函数，同时也实现了 Getter，因此可以直接传给 NewGroup
+type GetterCtxFunc func(ctx context.Context, key string) ([]byte, error)
+
+// GetContext 实现了GetterCtx接口的GetContext方法
+func (f GetterCtxFunc) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return f(ctx, key)
+}
+
+// Get 实现了Getter接口的Get方法，没有ctx时使用context.Background()
+func (f GetterCtxFunc) Get(key string) ([]byte, error) {
+	return f(context.Background(), key)
+}
+
+var (
+	_ Getter    = GetterCtxFunc(nil)
+	_ GetterCtx = GetterCtxFunc(nil)
+)
+
+// GetterTTL 允许Getter在返回数据的同时，为这条数据单独指定存活时间，
+// 返回的ttl为0时使用Group默认的ttl，小于0表示永不过期
+type GetterTTL interface {
+	GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error)
+}
+
+// GetterTTLFunc 是 GetterTTL 的接口型函数，同时也实现了 Getter，因此可以直接传给 NewGroup
+type GetterTTLFunc func(ctx context.Context, key string) ([]byte, time.Duration, error)
+
+// GetWithTTL 实现了GetterTTL接口的GetWithTTL方法
+func (f GetterTTLFunc) GetWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	return f(ctx, key)
+}
+
+// Get 实现了Getter接口的Get方法，忽略返回的ttl
+func (f GetterTTLFunc) Get(key string) ([]byte, error) {
+	b, _, err := f(context.Background(), key)
+	return b, err
+}
+
+var (
+	_ Getter    = GetterTTLFunc(nil)
+	_ GetterTTL = GetterTTLFunc(nil)
+)